@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+
+	"dyp_chain/blockchain"
+)
+
+// DefaultAdminAddr is where the admin routes are served unless configured
+const DefaultAdminAddr = "127.0.0.1:8081"
+
+// BlockGenerator mines count blocks on demand paying the rewards to address
+type BlockGenerator func(count int, address string) ([]*blockchain.Block, error)
+
+// Admin request and response types
+type (
+	GenerateRequest struct {
+		Blocks  int    `json:"blocks"`
+		Address string `json:"address"`
+	}
+
+	GenerateResponse struct {
+		BlockHashes []string `json:"block_hashes"`
+		Height      int      `json:"height"`
+	}
+)
+
+// isLoopbackHost reports whether host, without a port, names this machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminOnly lets through only requests addressed to a loopback host with a
+// JSON body. Browsers cannot send those from other sites without a CORS
+// preflight, which the admin server never answers, and a rebound DNS name
+// shows up in the Host header.
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopbackHost(host) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// StartAdmin serves the routes that change how this node behaves, such as
+// its signer votes or on-demand blocks made by generate, on addr. They carry
+// no authentication, so addr must be a loopback address and only local users
+// can reach them.
+func (s *Server) StartAdmin(addr string, generate BlockGenerator) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		log.Fatalf("Invalid admin address %q: %v", addr, err)
+	}
+	if !isLoopbackHost(host) {
+		log.Fatalf("Admin address %s must be a loopback address", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/clique/propose", adminOnly(s.handleProposeSigner))
+	mux.HandleFunc("/clique/discard", adminOnly(s.handleDiscardProposal))
+	mux.HandleFunc("/generate", adminOnly(handleGenerate(generate)))
+
+	log.Printf("Admin server starting on %s\n", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// handleGenerate mines the requested number of blocks on regtest networks
+func handleGenerate(generate BlockGenerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req GenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		blocks, err := generate(req.Blocks, req.Address)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp := GenerateResponse{BlockHashes: make([]string, len(blocks))}
+		for i, block := range blocks {
+			resp.BlockHashes[i] = hex.EncodeToString(block.Hash)
+			resp.Height = block.Height
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"dyp_chain/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Proof-of-authority request and response types
+type (
+	ProposeSignerRequest struct {
+		Address   string `json:"address"`
+		Authorize bool   `json:"authorize"` // true to add the address, false to remove it
+	}
+
+	DiscardProposalRequest struct {
+		Address string `json:"address"`
+	}
+
+	SignerVote struct {
+		Signer    string `json:"signer"`
+		Address   string `json:"address"`
+		Authorize bool   `json:"authorize"`
+		Height    int    `json:"height"`
+	}
+
+	SignersResponse struct {
+		Height    int             `json:"height"`
+		Signers   []string        `json:"signers"`
+		Votes     []SignerVote    `json:"votes"`     // Pending votes in the chain
+		Proposals map[string]bool `json:"proposals"` // Votes this node casts when sealing
+	}
+)
+
+// cliqueEngine returns the proof-of-authority engine, writing an error
+// response when the network runs another engine
+func (s *Server) cliqueEngine(w http.ResponseWriter) (*blockchain.CliqueEngine, bool) {
+	engine, ok := s.bc.Engine().(*blockchain.CliqueEngine)
+	if !ok {
+		http.Error(w, "Proof of authority is not enabled on this network", http.StatusNotFound)
+	}
+	return engine, ok
+}
+
+// handleGetSigners returns the current signer set and pending votes
+func (s *Server) handleGetSigners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	engine, ok := s.cliqueEngine(w)
+	if !ok {
+		return
+	}
+
+	tip := s.bc.GetLastBlock()
+	snap, err := engine.Snapshot(s.bc, &tip.BlockHeader)
+	if err != nil {
+		http.Error(w, "Failed to load signers: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := SignersResponse{
+		Height:    snap.Height,
+		Signers:   []string{},
+		Votes:     []SignerVote{},
+		Proposals: map[string]bool{},
+	}
+	for _, signer := range snap.SignerList() {
+		response.Signers = append(response.Signers, signer.Hex())
+	}
+	for _, vote := range snap.Votes {
+		response.Votes = append(response.Votes, SignerVote{
+			Signer:    vote.Signer.Hex(),
+			Address:   vote.Address.Hex(),
+			Authorize: vote.Authorize,
+			Height:    vote.Height,
+		})
+	}
+	for address, authorize := range engine.Proposals() {
+		response.Proposals[address.Hex()] = authorize
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleProposeSigner makes this node vote to add or remove a signer in the
+// blocks it seals
+func (s *Server) handleProposeSigner(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	engine, ok := s.cliqueEngine(w)
+	if !ok {
+		return
+	}
+
+	var req ProposeSignerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		return
+	}
+
+	engine.Propose(common.HexToAddress(req.Address), req.Authorize)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Proposal recorded",
+		"address":   common.HexToAddress(req.Address).Hex(),
+		"authorize": req.Authorize,
+	})
+}
+
+// handleDiscardProposal withdraws this node's vote on an address
+func (s *Server) handleDiscardProposal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	engine, ok := s.cliqueEngine(w)
+	if !ok {
+		return
+	}
+
+	var req DiscardProposalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		return
+	}
+
+	engine.Discard(common.HexToAddress(req.Address))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Proposal discarded",
+		"address": common.HexToAddress(req.Address).Hex(),
+	})
+}
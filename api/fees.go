@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"dyp_chain/mempool"
+)
+
+// FeeEstimateResponse recommends a fee rate for a confirmation target
+type FeeEstimateResponse struct {
+	Target     int     `json:"target"`       // Blocks to confirm within
+	FeePerByte float32 `json:"fee_per_byte"` // DYP per serialized byte
+	Source     string  `json:"source"`       // "history", or "minimum" without enough confirmed transactions
+}
+
+// handleEstimateFee returns the fee rate needed to confirm within the target
+// number of blocks, given by the target query parameter
+func (s *Server) handleEstimateFee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := mempool.DefaultConfirmTarget
+	if value := r.URL.Query().Get("target"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > mempool.MaxConfirmTarget {
+			http.Error(w, "target must be a number of blocks between 1 and "+strconv.Itoa(mempool.MaxConfirmTarget), http.StatusBadRequest)
+			return
+		}
+		target = n
+	}
+
+	feeRate, fromHistory := s.pool.EstimateFee(target)
+	source := "minimum"
+	if fromHistory {
+		source = "history"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(FeeEstimateResponse{
+		Target:     target,
+		FeePerByte: feeRate,
+		Source:     source,
+	})
+}
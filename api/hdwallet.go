@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"dyp_chain/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// HD wallet request and response types. Mnemonics and private keys never
+// reach the node: clients send the extended public key of an account, as
+// printed by the exportxpub command, or the addresses they derived.
+type (
+	DiscoverRequest struct {
+		XPub      string   `json:"xpub"`      // Extended public key of an account, m/44'/60'/account'
+		Addresses []string `json:"addresses"` // Addresses to report instead of scanning an account
+		GapLimit  int      `json:"gap_limit"` // Defaults to 20, used with xpub
+	}
+
+	DiscoveredAddressResponse struct {
+		Path            string  `json:"path,omitempty"`
+		Change          bool    `json:"change"`
+		Index           uint32  `json:"index"`
+		Address         string  `json:"address"`
+		Confirmed       float32 `json:"confirmed"`
+		PendingIncoming float32 `json:"pending_incoming"`
+		PendingOutgoing float32 `json:"pending_outgoing"`
+		Spendable       float32 `json:"spendable"`
+	}
+
+	DiscoverResponse struct {
+		Account   *uint32                     `json:"account,omitempty"` // Set when an xpub was scanned
+		Addresses []DiscoveredAddressResponse `json:"addresses"`
+		Spendable float32                     `json:"spendable"` // Total over the addresses
+	}
+)
+
+// handleDiscover scans the chain for the used addresses of an account given
+// by its extended public key, or reports the balances of a list of addresses
+func (s *Server) handleDiscover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DiscoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (req.XPub == "") == (len(req.Addresses) == 0) {
+		http.Error(w, "Either xpub or addresses is required", http.StatusBadRequest)
+		return
+	}
+
+	var resp DiscoverResponse
+	var found []blockchain.DiscoveredAddress
+	if req.XPub != "" {
+		if req.GapLimit < 0 || req.GapLimit > blockchain.MaxGapLimit {
+			http.Error(w, fmt.Sprintf("gap_limit must be between 1 and %d", blockchain.MaxGapLimit), http.StatusBadRequest)
+			return
+		}
+		accountKey, err := blockchain.ParseExtendedPublicKey(req.XPub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		account, err := accountKey.Account()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp.Account = &account
+
+		if found, err = blockchain.DiscoverAccount(s.bc, accountKey, req.GapLimit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if len(req.Addresses) > blockchain.MaxDiscoveredAddresses {
+			http.Error(w, fmt.Sprintf("At most %d addresses are accepted", blockchain.MaxDiscoveredAddresses), http.StatusBadRequest)
+			return
+		}
+		for _, address := range req.Addresses {
+			if !common.IsHexAddress(address) {
+				http.Error(w, "Invalid address format: "+address, http.StatusBadRequest)
+				return
+			}
+		}
+
+		balances := s.bc.GetPendingBalances(req.Addresses)
+		for _, address := range req.Addresses {
+			found = append(found, blockchain.DiscoveredAddress{Address: address, Balance: balances[address]})
+		}
+	}
+
+	resp.Addresses = []DiscoveredAddressResponse{}
+	for _, addr := range found {
+		resp.Addresses = append(resp.Addresses, DiscoveredAddressResponse{
+			Path:            addr.Path,
+			Change:          addr.Chain == blockchain.ChangeChain,
+			Index:           addr.Index,
+			Address:         addr.Address,
+			Confirmed:       addr.Balance.Confirmed,
+			PendingIncoming: addr.Balance.PendingIncoming,
+			PendingOutgoing: addr.Balance.PendingOutgoing,
+			Spendable:       addr.Balance.Spendable,
+		})
+		resp.Spendable += addr.Balance.Spendable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"dyp_chain/blockchain"
+)
+
+// HTLC request and response types
+type (
+	HTLCCreateRequest struct {
+		PrivateKey  string  `json:"private_key"`
+		FromAddress string  `json:"from"`      // Funds the HTLC and receives refunds
+		Recipient   string  `json:"recipient"` // Redeems with the preimage
+		Hash        string  `json:"hash"`      // Hex encoded 32-byte hash of the secret
+		HashType    string  `json:"hash_type"` // "sha256" (default) or "keccak256"
+		Timeout     int     `json:"timeout"`   // Block height from which the sender can refund
+		Amount      float32 `json:"amount"`
+		Fee         float32 `json:"fee"`
+	}
+
+	HTLCCreateResponse struct {
+		Address string `json:"address"`
+		TxID    string `json:"txId"`
+		Vout    int    `json:"vout"`
+		Timeout int    `json:"timeout"`
+	}
+
+	HTLCSpendRequest struct {
+		PrivateKey string  `json:"private_key"`
+		TxID       string  `json:"txId"` // Transaction holding the HTLC output
+		Vout       int     `json:"vout"`
+		Preimage   string  `json:"preimage"` // Hex encoded secret, redeem only
+		ToAddress  string  `json:"to"`       // Defaults to the signer
+		Fee        float32 `json:"fee"`
+	}
+
+	HTLCPreimageResponse struct {
+		Hash     string `json:"hash"`
+		Preimage string `json:"preimage"`
+	}
+)
+
+// handleCreateHTLC funds a new HTLC output
+func (s *Server) handleCreateHTLC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HTLCCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.HashType == "" {
+		req.HashType = blockchain.HTLCHashSHA256
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(req.Hash, "0x"))
+	if err != nil {
+		http.Error(w, "Invalid hash format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Timeout <= s.bc.GetHeight() {
+		http.Error(w, "Timeout must be above the current height", http.StatusBadRequest)
+		return
+	}
+
+	lock, err := blockchain.NewHTLCLock(req.HashType, hash, req.Recipient, req.FromAddress, req.Timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sendReq := SendRequest{
+		PrivateKey:  req.PrivateKey,
+		FromAddress: req.FromAddress,
+		ToAddress:   lock.Address(),
+		Amount:      req.Amount,
+		Fee:         &req.Fee,
+	}
+	if err := s.validateSendRequest(sendReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := blockchain.NewWalletFromPrivateKey(req.PrivateKey)
+	if err != nil {
+		http.Error(w, "Invalid private key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tx, err := blockchain.NewUnsignedUTXOTransaction(req.FromAddress, lock.Address(), req.Amount, req.Fee, wallet.PublicKey, blockchain.TxOptions{HTLC: lock}, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.bc.SignTransaction(tx, wallet.PrivateKey)
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HTLCCreateResponse{
+		Address: lock.Address(),
+		TxID:    hex.EncodeToString(tx.ID),
+		Vout:    0, // NewUnsignedUTXOTransaction puts the payment first
+		Timeout: lock.Timeout,
+	})
+}
+
+// handleRedeemHTLC spends an HTLC output by revealing its preimage
+func (s *Server) handleRedeemHTLC(w http.ResponseWriter, r *http.Request) {
+	s.handleSpendHTLC(w, r, true)
+}
+
+// handleRefundHTLC returns an expired HTLC output to its sender
+func (s *Server) handleRefundHTLC(w http.ResponseWriter, r *http.Request) {
+	s.handleSpendHTLC(w, r, false)
+}
+
+func (s *Server) handleSpendHTLC(w http.ResponseWriter, r *http.Request, redeem bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HTLCSpendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	txID, err := hex.DecodeString(req.TxID)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID format", http.StatusBadRequest)
+		return
+	}
+
+	var preimage []byte
+	if redeem {
+		preimage, err = hex.DecodeString(strings.TrimPrefix(req.Preimage, "0x"))
+		if err != nil || len(preimage) == 0 {
+			http.Error(w, "A hex encoded preimage is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := blockchain.NewHTLCSpendTransaction(req.PrivateKey, txID, req.Vout, preimage, req.ToAddress, req.Fee, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Transaction added to mempool",
+		"txId":    hex.EncodeToString(tx.ID),
+		"to":      tx.To,
+		"amount":  tx.Amount,
+	})
+}
+
+// handleGetHTLCPreimage returns the preimage revealed for a hash, letting the
+// other side of a swap claim its own HTLC
+func (s *Server) handleGetHTLCPreimage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hashHex := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/htlc/preimage/"), "0x")
+	hash, err := hex.DecodeString(hashHex)
+	if err != nil || len(hash) != 32 {
+		http.Error(w, "Invalid hash format", http.StatusBadRequest)
+		return
+	}
+
+	preimage, err := s.bc.FindHTLCPreimage(hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HTLCPreimageResponse{
+		Hash:     hex.EncodeToString(hash),
+		Preimage: hex.EncodeToString(preimage),
+	})
+}
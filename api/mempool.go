@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"dyp_chain/blockchain"
+)
+
+// Mempool request and response types
+type (
+	MempoolInfoResponse struct {
+		Size               int     `json:"size"`
+		Bytes              int     `json:"bytes"`
+		MaxBytes           int     `json:"max_bytes"`
+		MinFeePerByte      float32 `json:"min_fee_per_byte"`       // Lowest fee rate currently accepted
+		MinRelayFeePerByte float32 `json:"min_relay_fee_per_byte"` // Fee floor when the pool is not full
+		ExpirySeconds      int64   `json:"expiry_seconds"`
+	}
+
+	BumpFeeRequest struct {
+		TxID string  `json:"txId"`
+		Fee  float32 `json:"fee"` // New absolute fee
+	}
+
+	BumpFeeResponse struct {
+		BuildTransactionResponse
+		From     string `json:"from"`     // Sender whose key signs the replacement
+		Replaces string `json:"replaces"` // ID of the pending transaction it replaces
+	}
+)
+
+// handleMempoolInfo returns the pool size, byte usage and current minimum fee
+func (s *Server) handleMempoolInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := s.pool.Info()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MempoolInfoResponse{
+		Size:               info.Size,
+		Bytes:              info.Bytes,
+		MaxBytes:           info.MaxBytes,
+		MinFeePerByte:      info.MinFeePerByte,
+		MinRelayFeePerByte: s.bc.MempoolPolicy().MinRelayFeePerByte,
+		ExpirySeconds:      int64(info.Expiry.Seconds()),
+	})
+}
+
+// handleBumpFee builds an unsigned copy of a pending transaction paying a
+// higher fee. The sender signs the returned sighashes and submits it to
+// /transaction/submit, which replaces the original in the mempool.
+func (s *Server) handleBumpFee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BumpFeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	txID, err := hex.DecodeString(req.TxID)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	orig, ok := s.pool.Get(txID)
+	if !ok {
+		http.Error(w, "Transaction is not pending", http.StatusNotFound)
+		return
+	}
+
+	tx, err := blockchain.BumpFee(orig, req.Fee, s.bc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	built, err := s.unsignedTransactionResponse(tx)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BumpFeeResponse{
+		BuildTransactionResponse: built,
+		From:                     tx.From,
+		Replaces:                 hex.EncodeToString(orig.ID),
+	})
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"dyp_chain/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multisig request and response types
+type (
+	MultisigRequest struct {
+		Threshold int      `json:"threshold"`
+		Addresses []string `json:"addresses"`
+	}
+
+	MultisigResponse struct {
+		Address   string   `json:"address"`
+		Threshold int      `json:"threshold"`
+		Addresses []string `json:"addresses"`
+	}
+
+	MultisigFundRequest struct {
+		MultisigRequest
+		FromAddress string  `json:"from"`
+		Amount      float32 `json:"amount"`
+		Fee         float32 `json:"fee"`
+		PublicKey   string  `json:"public_key"` // Optional uncompressed public key of from, recorded in the inputs
+	}
+
+	MultisigFundResponse struct {
+		BuildTransactionResponse
+		Address string `json:"address"` // Multisig address the payment is locked to
+	}
+
+	MultisigSpendRequest struct {
+		MultisigRequest
+		ToAddress string  `json:"to"`
+		Amount    float32 `json:"amount"`
+		Fee       float32 `json:"fee"`
+		LockTime  int64   `json:"lock_time"`
+		Sequence  uint32  `json:"sequence"`
+	}
+
+	MultisigSignRequest struct {
+		Tx         string   `json:"tx"`         // Hex encoded serialized transaction
+		Signatures []string `json:"signatures"` // One co-signature per input over its sighash, in order
+	}
+
+	MultisigSubmitRequest struct {
+		Tx string `json:"tx"`
+	}
+
+	MultisigTxResponse struct {
+		TxID       string                  `json:"txId"`
+		Tx         string                  `json:"tx"`
+		Signatures int                     `json:"signatures"` // Fewest co-signatures on any input
+		Complete   bool                    `json:"complete"`
+		Inputs     []UnsignedInputResponse `json:"inputs"` // Digest each co-signer signs per input
+	}
+)
+
+// handleCreateMultisig derives the address of an m-of-n lock
+func (s *Server) handleCreateMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MultisigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock, err := blockchain.NewMultisigLock(req.Threshold, req.Addresses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MultisigResponse{
+		Address:   lock.Address(),
+		Threshold: lock.Threshold,
+		Addresses: lock.Addresses,
+	})
+}
+
+// handleFundMultisig builds an unsigned payment from a regular address into a
+// multisig output. The sender signs the returned sighashes and submits the
+// transaction to /transaction/submit, as with /transaction/build.
+func (s *Server) handleFundMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MultisigFundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock, err := blockchain.NewMultisigLock(req.Threshold, req.Addresses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment := SendRequest{
+		FromAddress: req.FromAddress,
+		ToAddress:   lock.Address(),
+		Amount:      req.Amount,
+		Fee:         &req.Fee,
+	}
+	if err := s.validatePayment(payment); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pubKey, err := parsePublicKey(req.PublicKey, req.FromAddress)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := blockchain.NewUnsignedUTXOTransaction(req.FromAddress, lock.Address(), req.Amount, req.Fee, pubKey, blockchain.TxOptions{Multisig: lock}, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	built, err := s.unsignedTransactionResponse(tx)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MultisigFundResponse{BuildTransactionResponse: built, Address: lock.Address()})
+}
+
+// handleSpendMultisig builds an unsigned spend of multisig outputs for the
+// co-signers to sign in turn
+func (s *Server) handleSpendMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MultisigSpendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lock, err := blockchain.NewMultisigLock(req.Threshold, req.Addresses)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 || req.Fee < 0 || req.LockTime < 0 {
+		http.Error(w, "Amount must be greater than 0 and Fee and LockTime cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.ToAddress) {
+		http.Error(w, "Invalid destination address format", http.StatusBadRequest)
+		return
+	}
+
+	opts := blockchain.TxOptions{LockTime: req.LockTime, Sequence: req.Sequence}
+	tx, err := blockchain.NewMultisigSpendTransaction(lock, req.ToAddress, req.Amount, req.Fee, opts, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.multisigTxResponse(tx))
+}
+
+// handleSignMultisig adds co-signatures made by the client to a multisig
+// spend. Co-signers sign the sighashes returned with the spend, the node never
+// sees their keys.
+func (s *Server) handleSignMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MultisigSignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := decodeTransaction(req.Tx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signatures, err := decodeSignatures(req.Signatures)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddCosignatures(tx, signatures); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.multisigTxResponse(tx))
+}
+
+// handleSubmitMultisig verifies a fully co-signed spend and adds it to the mempool
+func (s *Server) handleSubmitMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MultisigSubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := decodeTransaction(req.Tx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if tx.IsCoinbase() {
+		http.Error(w, "Coinbase transactions cannot be submitted", http.StatusBadRequest)
+		return
+	}
+
+	// The ID is derived from the content, never taken from the client
+	tx.ID = tx.UnsignedID()
+
+	if !s.bc.VerifyTransaction(tx) {
+		http.Error(w, "Transaction rejected: missing or invalid signatures", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SubmitTransactionResponse{
+		Message: "Transaction added to mempool",
+		TxID:    hex.EncodeToString(tx.ID),
+		Fee:     tx.Fee,
+	})
+}
+
+// Helper methods
+
+func (s *Server) multisigTxResponse(tx *blockchain.Transaction) MultisigTxResponse {
+	tx.ID = tx.UnsignedID()
+	resp := MultisigTxResponse{
+		TxID:       hex.EncodeToString(tx.ID),
+		Tx:         hex.EncodeToString(tx.Serialize()),
+		Signatures: countSignatures(tx),
+		Complete:   s.bc.VerifyTransaction(tx),
+	}
+	if hashes, err := s.bc.InputSignatureHashes(tx); err == nil {
+		for i, vin := range tx.Vin {
+			resp.Inputs = append(resp.Inputs, s.unsignedInput(vin, hashes[i]))
+		}
+	}
+	return resp
+}
+
+func decodeTransaction(encoded string) (*blockchain.Transaction, error) {
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid transaction hex")
+	}
+	return blockchain.DeserializeTransaction(data)
+}
+
+func countSignatures(tx *blockchain.Transaction) int {
+	count := -1
+	for _, vin := range tx.Vin {
+		if count == -1 || len(vin.Signatures) < count {
+			count = len(vin.Signatures)
+		}
+	}
+	if count == -1 {
+		return 0
+	}
+	return count
+}
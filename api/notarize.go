@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dyp_chain/blockchain"
+)
+
+// Notarization request and response types
+type (
+	NotarizeRequest struct {
+		PrivateKey  string  `json:"private_key"`
+		FromAddress string  `json:"from"` // Pays the fee
+		Hash        string  `json:"hash"` // Hex encoded document hash
+		Fee         float32 `json:"fee"`
+	}
+
+	NotarizationResponse struct {
+		Hash        string `json:"hash"`
+		TxID        string `json:"txId"`
+		Status      string `json:"status"` // "confirmed" or "pending"
+		BlockHash   string `json:"blockHash,omitempty"`
+		BlockHeight int    `json:"blockHeight"`
+		Timestamp   int64  `json:"timestamp"`
+	}
+)
+
+// handleNotarize anchors a document hash on chain in a data output
+func (s *Server) handleNotarize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req NotarizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := decodeDocumentHash(req.Hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := blockchain.NewDataTransaction(req.PrivateKey, req.FromAddress, hash, req.Fee, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Transaction added to mempool",
+		"hash":    hex.EncodeToString(hash),
+		"txId":    hex.EncodeToString(tx.ID),
+	})
+}
+
+// handleGetNotarization returns the block proving when a hash was anchored
+func (s *Server) handleGetNotarization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash, err := decodeDocumentHash(strings.TrimPrefix(r.URL.Path, "/notarize/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, block, err := s.bc.FindDataOutput(hash)
+	if err != nil {
+		http.Error(w, "Hash is not notarized", http.StatusNotFound)
+		return
+	}
+
+	response := NotarizationResponse{
+		Hash:   hex.EncodeToString(hash),
+		TxID:   hex.EncodeToString(tx.ID),
+		Status: "pending",
+	}
+	if block != nil {
+		response.Status = "confirmed"
+		response.BlockHash = hex.EncodeToString(block.Hash)
+		response.BlockHeight = block.Height
+		response.Timestamp = block.Timestamp
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func decodeDocumentHash(encoded string) ([]byte, error) {
+	hash, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil || len(hash) == 0 {
+		return nil, fmt.Errorf("Invalid hash format")
+	}
+	if len(hash) > blockchain.MaxDataOutputSize {
+		return nil, fmt.Errorf("Hash cannot exceed %d bytes", blockchain.MaxDataOutputSize)
+	}
+	return hash, nil
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"dyp_chain/blockchain"
+)
+
+// maxImportBodySize bounds an import request: a full block hex encoded
+const maxImportBodySize = 2*blockchain.MaxBlockSize + 64*1024
+
+// Block import request and response types
+type (
+	ImportBlockRequest struct {
+		Header string `json:"header"` // Hex encoded serialized header, seal included
+		Body   string `json:"body"`   // Hex encoded serialized transactions
+	}
+
+	ImportBlockResponse struct {
+		Hash   string `json:"hash,omitempty"`
+		Height int    `json:"height"` // Height of this node's chain after the import
+		Known  bool   `json:"known"`  // The block was already in the chain
+	}
+)
+
+// handleImportBlock adds a block sealed elsewhere, typically pushed by another
+// signer of a proof-of-authority network, to the chain. The block goes through
+// the same header, seal and transaction checks as locally sealed blocks. A
+// block that does not extend the tip is refused with 409 Conflict and the
+// current height, so the sender can push the blocks in between first.
+func (s *Server) handleImportBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImportBlockRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBodySize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	headerData, err := hex.DecodeString(req.Header)
+	if err != nil {
+		http.Error(w, "Invalid header hex", http.StatusBadRequest)
+		return
+	}
+	bodyData, err := hex.DecodeString(req.Body)
+	if err != nil {
+		http.Error(w, "Invalid body hex", http.StatusBadRequest)
+		return
+	}
+	block, err := blockchain.DecodeBlock(headerData, bodyData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ImportBlockResponse{Hash: hex.EncodeToString(block.Hash), Height: s.bc.GetHeight()}
+	status := http.StatusOK
+	if _, err := s.bc.GetBlockHeader(block.Hash); err == nil {
+		resp.Known = true
+	} else if block.Height > resp.Height+1 {
+		status = http.StatusConflict
+	} else if err := s.bc.AddBlock(block, block.Transactions); err != nil {
+		http.Error(w, "Block rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	} else {
+		resp.Height = block.Height
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dyp_chain/blockchain"
+	"dyp_chain/mempool"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Client-side signing request and response types
+type (
+	UTXOResponse struct {
+		TxID        string  `json:"txId"`
+		Vout        int     `json:"vout"`
+		Value       float32 `json:"value"`
+		TokenID     string  `json:"token_id,omitempty"`
+		TokenAmount uint64  `json:"token_amount,omitempty"`
+		Confirmed   bool    `json:"confirmed"` // False for outputs of pending transactions
+	}
+
+	UTXOsResponse struct {
+		Address string         `json:"address"`
+		UTXOs   []UTXOResponse `json:"utxos"`
+	}
+
+	BuildTransactionRequest struct {
+		SendRequest
+		PublicKey string `json:"public_key"` // Optional uncompressed public key of from, recorded in the inputs
+	}
+
+	UnsignedInputResponse struct {
+		TxID    string  `json:"txId"` // Spent output
+		Vout    int     `json:"vout"`
+		Value   float32 `json:"value"`
+		SigHash string  `json:"sighash"` // Digest to sign with the key of from
+	}
+
+	BuildTransactionResponse struct {
+		TxID   string                  `json:"txId"`
+		Tx     string                  `json:"tx"` // Hex encoded unsigned transaction
+		Fee    float32                 `json:"fee"`
+		Inputs []UnsignedInputResponse `json:"inputs"`
+	}
+
+	SubmitTransactionRequest struct {
+		Tx         string   `json:"tx"`         // Hex encoded transaction, signed or as built
+		Signatures []string `json:"signatures"` // Hex signatures of the inputs in order, when Tx is unsigned
+	}
+
+	SubmitTransactionResponse struct {
+		Message string  `json:"message"`
+		TxID    string  `json:"txId"`
+		Fee     float32 `json:"fee"`
+	}
+)
+
+// handleGetUTXOs returns the outputs an address can spend, those of pending
+// transactions included and those pending transactions spend left out
+func (s *Server) handleGetUTXOs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	address := strings.TrimPrefix(r.URL.Path, "/utxos/")
+	if !common.IsHexAddress(address) {
+		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		return
+	}
+
+	resp := UTXOsResponse{Address: address, UTXOs: []UTXOResponse{}}
+	for _, utxo := range s.bc.FindPendingUTXOs(address) {
+		_, pending := s.pool.Get(utxo.TxID)
+		resp.UTXOs = append(resp.UTXOs, UTXOResponse{
+			TxID:        hex.EncodeToString(utxo.TxID),
+			Vout:        utxo.Vout,
+			Value:       utxo.Output.Value,
+			TokenID:     utxo.Output.TokenID,
+			TokenAmount: utxo.Output.TokenAmount,
+			Confirmed:   !pending,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleBuildTransaction builds an unsigned payment and returns it with the
+// digest each input must sign, so the sender's key never leaves the client
+func (s *Server) handleBuildTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BuildTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.PrivateKey != "" {
+		http.Error(w, "private_key is not accepted, sign the returned sighashes instead", http.StatusBadRequest)
+		return
+	}
+	if err := s.validatePayment(req.SendRequest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pubKey, err := parsePublicKey(req.PublicKey, req.FromAddress)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := blockchain.TxOptions{
+		LockTime: req.LockTime,
+		Sequence: req.Sequence,
+	}
+	var tx *blockchain.Transaction
+	if req.Fee != nil {
+		tx, err = blockchain.NewUnsignedUTXOTransaction(req.FromAddress, req.ToAddress, req.Amount, *req.Fee, pubKey, opts, s.bc)
+	} else {
+		target := req.ConfTarget
+		if target == 0 {
+			target = mempool.DefaultConfirmTarget
+		}
+		feeRate, _ := s.pool.EstimateFee(target)
+		tx, err = blockchain.NewUnsignedUTXOTransactionWithFeeRate(req.FromAddress, req.ToAddress, req.Amount, feeRate, pubKey, opts, s.bc)
+	}
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.unsignedTransactionResponse(tx)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSubmitTransaction verifies a transaction signed by the client and adds
+// it to the mempool. The transaction is either fully signed, or as returned by
+// the build endpoint together with one signature per input.
+func (s *Server) handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SubmitTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := decodeTransaction(req.Tx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if tx.IsCoinbase() {
+		http.Error(w, "Coinbase transactions cannot be submitted", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Signatures) > 0 {
+		signatures, err := decodeSignatures(req.Signatures)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := tx.AttachSignatures(signatures); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The ID is derived from the content, never taken from the client
+	tx.ID = tx.UnsignedID()
+
+	if err := s.bc.CheckTransaction(tx, s.bc.GetPendingTransactions()); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SubmitTransactionResponse{
+		Message: "Transaction added to mempool",
+		TxID:    hex.EncodeToString(tx.ID),
+		Fee:     tx.Fee,
+	})
+}
+
+// unsignedTransactionResponse describes an unsigned transaction together with
+// the digest each of its inputs must sign
+func (s *Server) unsignedTransactionResponse(tx *blockchain.Transaction) (BuildTransactionResponse, error) {
+	hashes, err := s.bc.InputSignatureHashes(tx)
+	if err != nil {
+		return BuildTransactionResponse{}, err
+	}
+
+	resp := BuildTransactionResponse{
+		TxID: hex.EncodeToString(tx.ID),
+		Tx:   hex.EncodeToString(tx.Serialize()),
+		Fee:  tx.Fee,
+	}
+	for i, vin := range tx.Vin {
+		resp.Inputs = append(resp.Inputs, s.unsignedInput(vin, hashes[i]))
+	}
+	return resp, nil
+}
+
+// unsignedInput describes an input and the digest it must sign
+func (s *Server) unsignedInput(vin blockchain.TXInput, sigHash []byte) UnsignedInputResponse {
+	input := UnsignedInputResponse{
+		TxID:    hex.EncodeToString(vin.Txid),
+		Vout:    vin.Vout,
+		SigHash: hex.EncodeToString(sigHash),
+	}
+	if prevTX, err := s.bc.FindTransaction(vin.Txid); err == nil {
+		input.Value = prevTX.Vout[vin.Vout].Value
+	} else if prevTX, ok := s.pool.Get(vin.Txid); ok {
+		input.Value = prevTX.Vout[vin.Vout].Value
+	}
+	return input
+}
+
+// parsePublicKey decodes an optional hex public key and checks that it
+// belongs to address
+func parsePublicKey(encoded, address string) ([]byte, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	pubKey, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid public key")
+	}
+	key, err := crypto.UnmarshalPubkey(pubKey)
+	if err != nil || !strings.EqualFold(crypto.PubkeyToAddress(*key).Hex(), address) {
+		return nil, fmt.Errorf("Public key does not belong to the source address")
+	}
+	return pubKey, nil
+}
+
+// decodeSignatures decodes hex signatures sent by a client
+func decodeSignatures(encoded []string) ([][]byte, error) {
+	signatures := make([][]byte, len(encoded))
+	for i, sig := range encoded {
+		var err error
+		if signatures[i], err = hex.DecodeString(strings.TrimPrefix(sig, "0x")); err != nil {
+			return nil, fmt.Errorf("Invalid signature hex")
+		}
+	}
+	return signatures, nil
+}
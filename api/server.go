@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"dyp_chain/blockchain"
+	"dyp_chain/mempool"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/sethvargo/go-limiter"
@@ -22,6 +23,7 @@ import (
 type Server struct {
 	port    string
 	bc      *blockchain.Blockchain
+	pool    *mempool.Mempool
 	limiter limiter.Store
 }
 
@@ -37,16 +39,23 @@ type (
 	}
 
 	BalanceResponse struct {
-		Address string  `json:"address"`
-		Balance float32 `json:"balance"`
+		Address         string  `json:"address"`
+		Balance         float32 `json:"balance"` // Same as Confirmed
+		Confirmed       float32 `json:"confirmed"`
+		PendingIncoming float32 `json:"pending_incoming"`
+		PendingOutgoing float32 `json:"pending_outgoing"`
+		Spendable       float32 `json:"spendable"`
 	}
 
 	SendRequest struct {
-		PrivateKey  string  `json:"private_key"`
-		FromAddress string  `json:"from"`
-		ToAddress   string  `json:"to"`
-		Amount      float32 `json:"amount"`
-		Fee         float32 `json:"fee"`
+		PrivateKey  string   `json:"private_key"`
+		FromAddress string   `json:"from"`
+		ToAddress   string   `json:"to"`
+		Amount      float32  `json:"amount"`
+		Fee         *float32 `json:"fee"`         // Omit to pay the estimated fee for ConfTarget
+		ConfTarget  int      `json:"conf_target"` // Blocks to confirm within when the fee is estimated
+		LockTime    int64    `json:"lock_time"`   // Block height, or unix time at or above 500000000
+		Sequence    uint32   `json:"sequence"`    // Relative lock applied to every input
 	}
 
 	CreateBlockchainRequest struct {
@@ -85,8 +94,13 @@ type (
 		Height        int                   `json:"height"`
 		Hash          string                `json:"hash"`
 		PrevBlockHash string                `json:"prevBlockHash"`
+		MerkleRoot    string                `json:"merkleRoot"`
+		Version       int32                 `json:"version"`
+		Bits          int                   `json:"bits"`
 		Timestamp     int64                 `json:"timestamp"`
-		Nonce         int                   `json:"nonce"`
+		Nonce         uint64                `json:"nonce"`
+		Vote          string                `json:"vote,omitempty"`   // Signer vote on proof-of-authority networks
+		Signer        string                `json:"signer,omitempty"` // Sealer on proof-of-authority networks
 		Transactions  []TransactionResponse `json:"transactions"`
 	}
 
@@ -94,6 +108,23 @@ type (
 		Blocks []BlockResponse `json:"blocks"`
 	}
 
+	HeaderResponse struct {
+		Height        int    `json:"height"`
+		Hash          string `json:"hash"`
+		PrevBlockHash string `json:"prevBlockHash"`
+		MerkleRoot    string `json:"merkleRoot"`
+		Version       int32  `json:"version"`
+		Bits          int    `json:"bits"`
+		Timestamp     int64  `json:"timestamp"`
+		Nonce         uint64 `json:"nonce"`
+		Vote          string `json:"vote,omitempty"`
+		Signer        string `json:"signer,omitempty"`
+	}
+
+	HeaderListResponse struct {
+		Headers []HeaderResponse `json:"headers"`
+	}
+
 	TransactionDetailsResponse struct {
 		TxID        string  `json:"txId"`
 		From        string  `json:"from"`
@@ -108,7 +139,7 @@ type (
 )
 
 // NewServer creates a new server instance with rate limiting
-func NewServer(port string, bc *blockchain.Blockchain) (*Server, error) {
+func NewServer(port string, bc *blockchain.Blockchain, pool *mempool.Mempool) (*Server, error) {
 	// Create a new rate limiter that allows 2 requests per second
 	store, err := memorystore.New(&memorystore.Config{
 		Tokens:   2,           // Number of tokens allowed per interval
@@ -121,6 +152,7 @@ func NewServer(port string, bc *blockchain.Blockchain) (*Server, error) {
 	return &Server{
 		port:    port,
 		bc:      bc,
+		pool:    pool,
 		limiter: store,
 	}, nil
 }
@@ -176,16 +208,25 @@ func (s *Server) handleGetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasSuffix(address, "/tokens") {
+		s.handleGetTokenBalances(w, strings.TrimSuffix(address, "/tokens"))
+		return
+	}
+
 	if !common.IsHexAddress(address) {
 		http.Error(w, "Invalid address format", http.StatusBadRequest)
 		return
 	}
 
-	balance := s.bc.GetBalance(address)
+	balance := s.bc.GetPendingBalance(address)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(BalanceResponse{
-		Address: address,
-		Balance: balance,
+		Address:         address,
+		Balance:         balance.Confirmed,
+		Confirmed:       balance.Confirmed,
+		PendingIncoming: balance.PendingIncoming,
+		PendingOutgoing: balance.PendingOutgoing,
+		Spendable:       balance.Spendable,
 	})
 }
 
@@ -277,10 +318,14 @@ func (s *Server) handleGetAllBlocks(w http.ResponseWriter, r *http.Request) {
 			Height:        block.Height,
 			Hash:          hex.EncodeToString(block.Hash),
 			PrevBlockHash: hex.EncodeToString(block.PrevBlockHash),
+			MerkleRoot:    hex.EncodeToString(block.MerkleRoot),
+			Version:       block.Version,
+			Bits:          block.Bits,
 			Timestamp:     block.Timestamp,
 			Nonce:         block.Nonce,
 			Transactions:  txResponses,
 		}
+		blockResponse.Vote, blockResponse.Signer = sealFields(&block.BlockHeader)
 
 		response.Blocks = append(response.Blocks, blockResponse)
 
@@ -322,15 +367,71 @@ func (s *Server) handleGetSpecificBlock(w http.ResponseWriter, r *http.Request)
 		Height:        foundBlock.Height,
 		Hash:          hex.EncodeToString(foundBlock.Hash),
 		PrevBlockHash: hex.EncodeToString(foundBlock.PrevBlockHash),
+		MerkleRoot:    hex.EncodeToString(foundBlock.MerkleRoot),
+		Version:       foundBlock.Version,
+		Bits:          foundBlock.Bits,
 		Timestamp:     foundBlock.Timestamp,
 		Nonce:         foundBlock.Nonce,
 		Transactions:  txResponses,
 	}
+	blockResponse.Vote, blockResponse.Signer = sealFields(&foundBlock.BlockHeader)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(blockResponse)
 }
 
+func (s *Server) handleGetAllHeaders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bci := s.bc.Iterator()
+	response := HeaderListResponse{
+		Headers: make([]HeaderResponse, 0),
+	}
+
+	for {
+		header := bci.NextHeader()
+		response.Headers = append(response.Headers, convertHeader(header))
+
+		if len(header.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) handleGetSpecificHeader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	blockHash := strings.TrimPrefix(r.URL.Path, "/header/")
+	if blockHash == "" {
+		http.Error(w, "Block hash is required", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := hex.DecodeString(blockHash)
+	if err != nil {
+		http.Error(w, "Invalid block hash format", http.StatusBadRequest)
+		return
+	}
+
+	header, err := s.bc.GetBlockHeader(hash)
+	if err != nil {
+		http.Error(w, "Block not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertHeader(header))
+}
+
 func (s *Server) handleSendTransaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -348,23 +449,50 @@ func (s *Server) handleSendTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx := blockchain.NewUTXOTransaction(req.PrivateKey, req.FromAddress, req.ToAddress, req.Amount, req.Fee, s.bc)
-	if tx == nil {
-		http.Error(w, "Failed to create transaction: insufficient funds", http.StatusBadRequest)
+	opts := blockchain.TxOptions{
+		LockTime: req.LockTime,
+		Sequence: req.Sequence,
+	}
+	wallet, err := blockchain.NewWalletFromPrivateKey(req.PrivateKey)
+	if err != nil {
+		http.Error(w, "Invalid private key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var tx *blockchain.Transaction
+	if req.Fee != nil {
+		tx, err = blockchain.NewUnsignedUTXOTransaction(req.FromAddress, req.ToAddress, req.Amount, *req.Fee, wallet.PublicKey, opts, s.bc)
+	} else {
+		target := req.ConfTarget
+		if target == 0 {
+			target = mempool.DefaultConfirmTarget
+		}
+		feeRate, _ := s.pool.EstimateFee(target)
+		tx, err = blockchain.NewUnsignedUTXOTransactionWithFeeRate(req.FromAddress, req.ToAddress, req.Amount, feeRate, wallet.PublicKey, opts, s.bc)
+	}
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.bc.SignTransaction(tx, wallet.PrivateKey)
 
-	s.bc.AddTransaction(tx)
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Transaction added to mempool",
+		"txId":    hex.EncodeToString(tx.ID),
 		"details": map[string]interface{}{
 			"transaction": map[string]interface{}{
-				"from":   req.FromAddress,
-				"to":     req.ToAddress,
-				"amount": req.Amount,
-				"fee":    req.Fee,
+				"from":      req.FromAddress,
+				"to":        req.ToAddress,
+				"amount":    req.Amount,
+				"fee":       tx.Fee,
+				"lock_time": req.LockTime,
+				"sequence":  req.Sequence,
 			},
 		},
 	})
@@ -482,6 +610,36 @@ func (s *Server) convertTransactions(block *blockchain.Block) []TransactionRespo
 	return txResponses
 }
 
+func convertHeader(header *blockchain.BlockHeader) HeaderResponse {
+	vote, signer := sealFields(header)
+	return HeaderResponse{
+		Height:        header.Height,
+		Hash:          hex.EncodeToString(header.Hash()),
+		PrevBlockHash: hex.EncodeToString(header.PrevBlockHash),
+		MerkleRoot:    hex.EncodeToString(header.MerkleRoot),
+		Version:       header.Version,
+		Bits:          header.Bits,
+		Timestamp:     header.Timestamp,
+		Nonce:         header.Nonce,
+		Vote:          vote,
+		Signer:        signer,
+	}
+}
+
+// sealFields returns the vote and signer of a signed header, or empty strings
+// for proof-of-work headers
+func sealFields(header *blockchain.BlockHeader) (vote, signer string) {
+	if len(header.Vote) != 0 {
+		vote = common.BytesToAddress(header.Vote).Hex()
+	}
+	if len(header.Signature) != 0 {
+		if address, err := blockchain.RecoverSigner(header); err == nil {
+			signer = address.Hex()
+		}
+	}
+	return vote, signer
+}
+
 func (s *Server) findBlock(hash []byte) *blockchain.Block {
 	bci := s.bc.Iterator()
 	for {
@@ -501,14 +659,40 @@ func (s *Server) validateSendRequest(req SendRequest) error {
 		return fmt.Errorf("PrivateKey, FromAddress and ToAddress are required")
 	}
 
+	return s.validatePayment(req)
+}
+
+// validatePayment checks the payment fields of a send request, everything
+// but the key
+func (s *Server) validatePayment(req SendRequest) error {
+	if req.ToAddress == "" || req.FromAddress == "" {
+		return fmt.Errorf("FromAddress and ToAddress are required")
+	}
+
 	if req.Amount <= 0 {
 		return fmt.Errorf("Amount must be greater than 0")
 	}
 
-	if req.Fee < 0 {
+	if dust := s.bc.MempoolPolicy().DustThreshold; req.Amount < dust {
+		return fmt.Errorf("Amount must be at least the dust threshold of %f DYP", dust)
+	}
+
+	if req.Fee != nil && *req.Fee < 0 {
 		return fmt.Errorf("Fee cannot be negative")
 	}
 
+	if req.ConfTarget < 0 || req.ConfTarget > mempool.MaxConfirmTarget {
+		return fmt.Errorf("ConfTarget must be between 1 and %d blocks", mempool.MaxConfirmTarget)
+	}
+
+	if req.LockTime < 0 {
+		return fmt.Errorf("LockTime cannot be negative")
+	}
+
+	if !common.IsHexAddress(req.FromAddress) {
+		return fmt.Errorf("Invalid source address format")
+	}
+
 	if !common.IsHexAddress(req.ToAddress) {
 		return fmt.Errorf("Invalid destination address format")
 	}
@@ -529,8 +713,33 @@ func (s *Server) Start() {
 	mux.HandleFunc("/history/", middleware(s.handleGetTransactionHistory))
 	mux.HandleFunc("/blocks", middleware(s.handleGetAllBlocks))
 	mux.HandleFunc("/block/", middleware(s.handleGetSpecificBlock))
+	mux.HandleFunc("/blocks/import", middleware(s.handleImportBlock))
+	mux.HandleFunc("/headers", middleware(s.handleGetAllHeaders))
+	mux.HandleFunc("/header/", middleware(s.handleGetSpecificHeader))
 	mux.HandleFunc("/transaction", middleware(s.handleSendTransaction))
 	mux.HandleFunc("/transaction/", middleware(s.handleGetTransaction))
+	mux.HandleFunc("/transaction/bumpfee", middleware(s.handleBumpFee))
+	mux.HandleFunc("/transaction/build", middleware(s.handleBuildTransaction))
+	mux.HandleFunc("/transaction/submit", middleware(s.handleSubmitTransaction))
+	mux.HandleFunc("/utxos/", middleware(s.handleGetUTXOs))
+	mux.HandleFunc("/multisig", middleware(s.handleCreateMultisig))
+	mux.HandleFunc("/multisig/fund", middleware(s.handleFundMultisig))
+	mux.HandleFunc("/multisig/spend", middleware(s.handleSpendMultisig))
+	mux.HandleFunc("/multisig/sign", middleware(s.handleSignMultisig))
+	mux.HandleFunc("/multisig/submit", middleware(s.handleSubmitMultisig))
+	mux.HandleFunc("/htlc", middleware(s.handleCreateHTLC))
+	mux.HandleFunc("/htlc/redeem", middleware(s.handleRedeemHTLC))
+	mux.HandleFunc("/htlc/refund", middleware(s.handleRefundHTLC))
+	mux.HandleFunc("/htlc/preimage/", middleware(s.handleGetHTLCPreimage))
+	mux.HandleFunc("/notarize", middleware(s.handleNotarize))
+	mux.HandleFunc("/notarize/", middleware(s.handleGetNotarization))
+	mux.HandleFunc("/tokens", middleware(s.handleIssueToken))
+	mux.HandleFunc("/tokens/send", middleware(s.handleSendToken))
+	mux.HandleFunc("/tokens/", middleware(s.handleGetToken))
+	mux.HandleFunc("/mempool/info", middleware(s.handleMempoolInfo))
+	mux.HandleFunc("/fees/estimate", middleware(s.handleEstimateFee))
+	mux.HandleFunc("/hdwallet/discover", middleware(s.handleDiscover))
+	mux.HandleFunc("/clique/signers", middleware(s.handleGetSigners))
 
 	log.Printf("Server starting on port %s\n", s.port)
 	log.Fatal(http.ListenAndServe(":"+s.port, mux))
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"dyp_chain/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Token request and response types
+type (
+	IssueTokenRequest struct {
+		PrivateKey  string  `json:"private_key"`
+		FromAddress string  `json:"from"` // Issuer, receives the whole supply
+		Name        string  `json:"name"`
+		Symbol      string  `json:"symbol"`
+		Supply      uint64  `json:"supply"`
+		Metadata    string  `json:"metadata"`
+		Fee         float32 `json:"fee"`
+	}
+
+	SendTokenRequest struct {
+		PrivateKey  string  `json:"private_key"`
+		FromAddress string  `json:"from"`
+		ToAddress   string  `json:"to"`
+		TokenID     string  `json:"token_id"`
+		Amount      uint64  `json:"amount"`
+		Fee         float32 `json:"fee"` // Paid in DYP
+	}
+
+	TokenResponse struct {
+		TokenID  string `json:"tokenId"`
+		Name     string `json:"name"`
+		Symbol   string `json:"symbol"`
+		Supply   uint64 `json:"supply"`
+		Metadata string `json:"metadata"`
+		Issuer   string `json:"issuer"`
+		TxID     string `json:"txId"`
+		Height   int    `json:"height"`
+	}
+
+	TokenBalance struct {
+		TokenID string `json:"tokenId"`
+		Symbol  string `json:"symbol"`
+		Amount  uint64 `json:"amount"`
+	}
+
+	TokenBalancesResponse struct {
+		Address string         `json:"address"`
+		Tokens  []TokenBalance `json:"tokens"`
+	}
+)
+
+// handleIssueToken creates a new token owned by the issuer
+func (s *Server) handleIssueToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req IssueTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issuance := blockchain.TokenIssuance{
+		Name:     req.Name,
+		Symbol:   req.Symbol,
+		Supply:   req.Supply,
+		Metadata: req.Metadata,
+	}
+	tx, err := blockchain.NewTokenIssuanceTransaction(req.PrivateKey, req.FromAddress, issuance, req.Fee, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Transaction added to mempool",
+		"tokenId": tx.IssuedTokenID(),
+		"txId":    hex.EncodeToString(tx.ID),
+	})
+}
+
+// handleSendToken transfers tokens between addresses
+func (s *Server) handleSendToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SendTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := blockchain.NewTokenTransaction(req.PrivateKey, req.FromAddress, req.ToAddress, strings.ToLower(req.TokenID), req.Amount, req.Fee, s.bc)
+	if err != nil {
+		http.Error(w, "Failed to create transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bc.AddTransaction(tx); err != nil {
+		http.Error(w, "Transaction rejected: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Transaction added to mempool",
+		"txId":    hex.EncodeToString(tx.ID),
+	})
+}
+
+// handleGetToken returns the definition of a token
+func (s *Server) handleGetToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenID := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/tokens/"))
+	if tokenID == "" {
+		http.Error(w, "Token ID is required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.bc.FindToken(tokenID)
+	if err != nil {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TokenResponse{
+		TokenID:  token.ID,
+		Name:     token.Name,
+		Symbol:   token.Symbol,
+		Supply:   token.Supply,
+		Metadata: token.Metadata,
+		Issuer:   token.Issuer,
+		TxID:     hex.EncodeToString(token.TxID),
+		Height:   token.Height,
+	})
+}
+
+// handleGetTokenBalances returns the tokens held by an address; it is served
+// under /balance/{address}/tokens
+func (s *Server) handleGetTokenBalances(w http.ResponseWriter, address string) {
+	if !common.IsHexAddress(address) {
+		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		return
+	}
+
+	response := TokenBalancesResponse{Address: address, Tokens: []TokenBalance{}}
+	for tokenID, amount := range s.bc.GetTokenBalances(address) {
+		balance := TokenBalance{TokenID: tokenID, Amount: amount}
+		if token, err := s.bc.FindToken(tokenID); err == nil {
+			balance.Symbol = token.Symbol
+		}
+		response.Tokens = append(response.Tokens, balance)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
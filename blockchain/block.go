@@ -3,67 +3,169 @@ package blockchain
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/gob"
+	"fmt"
 	"time"
 )
 
-// Block represents a block in the blockchain
-type Block struct {
-	Timestamp     int64
-	Transactions  []*Transaction
+// BlockVersion is the version written into new block headers
+const BlockVersion = 1
+
+// headerSize is the length of a serialized BlockHeader in bytes
+const headerSize = 4 + 32 + 32 + 8 + 8 + 8 + 8
+
+// sealSize is the length of the vote and signature that signed headers append
+// to the serialized header
+const sealSize = 20 + 65
+
+// BlockHeader holds the consensus fields of a block. The block hash is the
+// SHA-256 of the serialized header, so the header alone identifies a block.
+type BlockHeader struct {
+	Version       int32
 	PrevBlockHash []byte
-	Hash          []byte
-	Nonce         int
+	MerkleRoot    []byte
+	Timestamp     int64
+	Bits          int
+	Nonce         uint64
 	Height        int
-}
 
-// NewBlock creates and returns a new Block without mining
-func NewBlock(transactions []*Transaction, prevBlockHash []byte, height int) *Block {
-	block := &Block{
-		Timestamp:     time.Now().Unix(),
-		Transactions:  transactions,
-		PrevBlockHash: prevBlockHash,
-		Hash:          []byte{},
-		Nonce:         0,
-		Height:        height,
-	}
-	return block
+	// Vote and Signature are only used by signed (proof-of-authority)
+	// headers. Vote is the address proposed for addition to or removal from
+	// the signer set, and Signature is the sealer's signature of SealHash.
+	Vote      []byte
+	Signature []byte
 }
 
-// MineBlock performs proof of work on the block
-func (b *Block) MineBlock() {
-	pow := NewProofOfWork(b)
-	nonce, hash := pow.Run()
-	b.Hash = hash[:]
-	b.Nonce = nonce
+// Block represents a block in the blockchain
+type Block struct {
+	BlockHeader
+	Hash         []byte
+	Transactions []*Transaction
 }
 
-// NewGenesisBlock creates and returns genesis Block
-func NewGenesisBlock(coinbase *Transaction) *Block {
-	block := NewBlock([]*Transaction{coinbase}, []byte{}, 0)
-	block.MineBlock() // Genesis block still needs to be mined
+// NewBlock creates and returns a new Block at the given difficulty without sealing it
+func NewBlock(transactions []*Transaction, prevBlockHash []byte, height int, bits int) *Block {
+	block := &Block{
+		BlockHeader: BlockHeader{
+			Version:       BlockVersion,
+			PrevBlockHash: prevBlockHash,
+			Timestamp:     time.Now().Unix(),
+			Bits:          bits,
+			Nonce:         0,
+			Height:        height,
+		},
+		Hash:         []byte{},
+		Transactions: transactions,
+	}
+	block.MerkleRoot = block.HashTransactions()
 	return block
 }
 
-// HashTransactions returns a hash of the transactions in the block
+// HashTransactions returns the merkle root of the transactions in the block
 func (b *Block) HashTransactions() []byte {
 	var txHashes [][]byte
-	var txHash [32]byte
 
 	for _, tx := range b.Transactions {
 		txHashes = append(txHashes, tx.ID)
 	}
-	txHash = sha256.Sum256(bytes.Join(txHashes, []byte{}))
 
-	return txHash[:]
+	return MerkleRoot(txHashes)
+}
+
+// Serialize encodes the header into its binary form. Unsigned headers have a
+// fixed size; signed headers append the vote and signature.
+func (h *BlockHeader) Serialize() []byte {
+	data := h.serializeUnsealed()
+	if len(h.Vote) == 0 && len(h.Signature) == 0 {
+		return data
+	}
+
+	seal := make([]byte, sealSize)
+	copy(seal, h.Vote)
+	copy(seal[20:], h.Signature)
+	return append(data, seal...)
+}
+
+// SealHash returns the hash a proof-of-authority signer signs: the header
+// including its vote but without the signature
+func (h *BlockHeader) SealHash() []byte {
+	vote := make([]byte, 20)
+	copy(vote, h.Vote)
+	hash := sha256.Sum256(append(h.serializeUnsealed(), vote...))
+	return hash[:]
+}
+
+// serializeUnsealed encodes the fixed-size fields shared by every header
+func (h *BlockHeader) serializeUnsealed() []byte {
+	var buf bytes.Buffer
+
+	version := make([]byte, 4)
+	binary.BigEndian.PutUint32(version, uint32(h.Version))
+	buf.Write(version)
+
+	// The genesis block has no parent and is encoded with an all-zero hash
+	prevHash := make([]byte, 32)
+	copy(prevHash, h.PrevBlockHash)
+	buf.Write(prevHash)
+
+	merkleRoot := make([]byte, 32)
+	copy(merkleRoot, h.MerkleRoot)
+	buf.Write(merkleRoot)
+
+	buf.Write(IntToHex(h.Timestamp))
+	buf.Write(IntToHex(int64(h.Bits)))
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, h.Nonce)
+	buf.Write(nonce)
+	buf.Write(IntToHex(int64(h.Height)))
+
+	return buf.Bytes()
+}
+
+// Hash returns the block hash defined by the header
+func (h *BlockHeader) Hash() []byte {
+	hash := sha256.Sum256(h.Serialize())
+	return hash[:]
+}
+
+// DeserializeHeader decodes a header produced by BlockHeader.Serialize
+func DeserializeHeader(d []byte) (*BlockHeader, error) {
+	if len(d) != headerSize && len(d) != headerSize+sealSize {
+		return nil, fmt.Errorf("invalid header length %d", len(d))
+	}
+
+	header := &BlockHeader{
+		Version:       int32(binary.BigEndian.Uint32(d[0:4])),
+		PrevBlockHash: append([]byte{}, d[4:36]...),
+		MerkleRoot:    append([]byte{}, d[36:68]...),
+		Timestamp:     int64(binary.BigEndian.Uint64(d[68:76])),
+		Bits:          int(binary.BigEndian.Uint64(d[76:84])),
+		Nonce:         binary.BigEndian.Uint64(d[84:92]),
+		Height:        int(binary.BigEndian.Uint64(d[92:100])),
+	}
+
+	if header.Height == 0 {
+		header.PrevBlockHash = []byte{}
+	}
+
+	if len(d) > headerSize {
+		seal := d[headerSize:]
+		if !bytes.Equal(seal[:20], make([]byte, 20)) {
+			header.Vote = append([]byte{}, seal[:20]...)
+		}
+		header.Signature = append([]byte{}, seal[20:]...)
+	}
+
+	return header, nil
 }
 
-// Serialize serializes the block
-func (b *Block) Serialize() []byte {
+// SerializeBody serializes the block body (its transactions)
+func (b *Block) SerializeBody() []byte {
 	var result bytes.Buffer
 	encoder := gob.NewEncoder(&result)
 
-	err := encoder.Encode(b)
+	err := encoder.Encode(b.Transactions)
 	if err != nil {
 		panic(err)
 	}
@@ -71,15 +173,32 @@ func (b *Block) Serialize() []byte {
 	return result.Bytes()
 }
 
-// DeserializeBlock deserializes a block
-func DeserializeBlock(d []byte) *Block {
-	var block Block
-
-	decoder := gob.NewDecoder(bytes.NewReader(d))
-	err := decoder.Decode(&block)
+// DeserializeBlock rebuilds a block from its stored header and body
+func DeserializeBlock(headerData, bodyData []byte) *Block {
+	block, err := DecodeBlock(headerData, bodyData)
 	if err != nil {
 		panic(err)
 	}
+	return block
+}
+
+// DecodeBlock rebuilds a block from a serialized header and body received
+// from elsewhere, such as a peer
+func DecodeBlock(headerData, bodyData []byte) (*Block, error) {
+	header, err := DeserializeHeader(headerData)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []*Transaction
+	decoder := gob.NewDecoder(bytes.NewReader(bodyData))
+	if err := decoder.Decode(&transactions); err != nil {
+		return nil, fmt.Errorf("invalid block body: %v", err)
+	}
 
-	return &block
+	return &Block{
+		BlockHeader:  *header,
+		Hash:         header.Hash(),
+		Transactions: transactions,
+	}, nil
 }
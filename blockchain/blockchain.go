@@ -29,27 +29,31 @@ func init() {
 }
 
 const blocksBucket = "blocks"
+const headersBucket = "headers"
 const genesisCoinbaseData = "Dyphira Genesis Block"
 
 // Blockchain represents a blockchain
 type Blockchain struct {
-	tip     []byte
-	DB      *bolt.DB
-	mempool []*Transaction
+	tip       []byte
+	DB        *bolt.DB
+	txPool    TxPool
+	listeners []ChainListener
+	engine    ConsensusEngine
+	policy    MempoolPolicy
 }
 
-// CreateBlockchain creates a new blockchain DB
-func CreateBlockchain(address string) *Blockchain {
-	if dbExists() {
+// CreateBlockchain creates a new blockchain DB starting from the given genesis
+func CreateBlockchain(genesisConfig *Genesis) *Blockchain {
+	if DBExists() {
 		fmt.Println("Blockchain already exists.")
 		os.Exit(1)
 	}
 
 	var tip []byte
 
-	// Create genesis block with 50 DYP reward
-	cbtx := NewCoinbaseTx(address, genesisCoinbaseData, true, 0)
-	genesis := NewGenesisBlock(cbtx)
+	engine := NewEngine(activeNetwork)
+	genesis := genesisConfig.ToBlock(engine)
+	log.Printf("Created genesis block %x for chain ID %d", genesis.Hash, genesisConfig.ChainID)
 
 	db, err := bolt.Open(dbFile, 0600, nil)
 	if err != nil {
@@ -61,8 +65,12 @@ func CreateBlockchain(address string) *Blockchain {
 		if err != nil {
 			log.Panic(err)
 		}
+		_, err = tx.CreateBucket([]byte(headersBucket))
+		if err != nil {
+			log.Panic(err)
+		}
 
-		err = b.Put(genesis.Hash, genesis.Serialize())
+		err = putBlock(tx, genesis)
 		if err != nil {
 			log.Panic(err)
 		}
@@ -79,44 +87,40 @@ func CreateBlockchain(address string) *Blockchain {
 		log.Panic(err)
 	}
 
-	bc := Blockchain{tip, db, nil}
+	bc := Blockchain{tip: tip, DB: db, engine: engine, policy: DefaultMempoolPolicy}
 
 	return &bc
 }
 
-// NewBlockchain creates a new Blockchain with genesis Block
+// NewBlockchain opens the existing blockchain of the active network
 func NewBlockchain() *Blockchain {
-	if !dbExists() {
-		log.Panic("No existing blockchain found. Create one first using CreateBlockchain(address)")
+	if !DBExists() {
+		log.Panic("No existing blockchain found. Create one first using CreateBlockchain")
 	}
 
 	var tip []byte
+	engine := NewEngine(activeNetwork)
 	db, err := bolt.Open(dbFile, 0600, nil)
 
 	if err != nil {
 		log.Panic(err)
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
+		if b == nil || b.Get([]byte("l")) == nil {
+			return fmt.Errorf("blockchain database %s has no blocks. Remove it and restart", dbFile)
+		}
+		if tx.Bucket([]byte(headersBucket)) == nil {
+			return fmt.Errorf("blockchain database uses the old block format without separate headers. Remove it and resync")
+		}
+		tip = append([]byte{}, b.Get([]byte("l"))...)
 
-		if b == nil {
-			genesis := NewGenesisBlock(NewCoinbaseTx("Genesis", genesisCoinbaseData, true, 0))
-			b, err := tx.CreateBucket([]byte(blocksBucket))
-			if err != nil {
-				log.Panic(err)
-			}
-			err = b.Put(genesis.Hash, genesis.Serialize())
-			if err != nil {
-				log.Panic(err)
-			}
-			err = b.Put([]byte("l"), genesis.Hash)
-			if err != nil {
-				log.Panic(err)
-			}
-			tip = genesis.Hash
-		} else {
-			tip = b.Get([]byte("l"))
+		// A database created from another genesis belongs to another chain
+		want := activeNetwork.Genesis.ToBlock(engine).Hash
+		if got := genesisHash(tx, tip); !bytes.Equal(got, want) {
+			return fmt.Errorf("blockchain database %s has genesis %x, the %s genesis is %x. Remove it and resync",
+				dbFile, got, activeNetwork.Name, want)
 		}
 
 		return nil
@@ -127,29 +131,48 @@ func NewBlockchain() *Blockchain {
 	}
 
 	bc := &Blockchain{
-		tip:     tip,
-		DB:      db,
-		mempool: make([]*Transaction, 0),
+		tip:    tip,
+		DB:     db,
+		engine: engine,
+		policy: DefaultMempoolPolicy,
 	}
 
 	return bc
 }
 
+// Engine returns the consensus engine the chain validates blocks with
+func (bc *Blockchain) Engine() ConsensusEngine {
+	return bc.engine
+}
+
 // AddBlock adds a mined block to the blockchain
 func (bc *Blockchain) AddBlock(block *Block, transactions []*Transaction) error {
-	err := bc.DB.Update(func(tx *bolt.Tx) error {
+	if err := validateHeader(block); err != nil {
+		return err
+	}
+	if err := bc.engine.VerifyHeader(bc, &block.BlockHeader); err != nil {
+		return err
+	}
+
+	fees, err := bc.verifyBlockTransactions(block)
+	if err != nil {
+		return err
+	}
+	if err := bc.validateCoinbase(block, fees); err != nil {
+		return err
+	}
+
+	err = bc.DB.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 
 		// Check if block already exists
-		blockInDb := b.Get(block.Hash)
-		if blockInDb != nil {
+		if getHeader(tx, block.Hash) != nil {
 			return fmt.Errorf("block already exists")
 		}
 
 		// Get the current height
 		lastHash := b.Get([]byte("l"))
-		lastBlockData := b.Get(lastHash)
-		lastBlock := DeserializeBlock(lastBlockData)
+		lastBlock := getHeader(tx, lastHash)
 
 		// Verify block height is correct
 		expectedHeight := lastBlock.Height + 1
@@ -162,8 +185,7 @@ func (bc *Blockchain) AddBlock(block *Block, transactions []*Transaction) error
 			return fmt.Errorf("block does not link to current tip")
 		}
 
-		blockData := block.Serialize()
-		err := b.Put(block.Hash, blockData)
+		err := putBlock(tx, block)
 		if err != nil {
 			log.Printf("Failed to add block to chain: %v", err)
 			return err
@@ -180,13 +202,141 @@ func (bc *Blockchain) AddBlock(block *Block, transactions []*Transaction) error
 	})
 
 	if err == nil {
-		// Clear the mined transactions from mempool only if the block was successfully added
-		bc.ClearTransactionsFromMempool(transactions)
+		// Let the mempool evict confirmed and conflicting transactions
+		bc.notifyBlockConnected(block)
 	}
 
 	return err
 }
 
+// validateHeader checks the header version and that the block hash and merkle
+// root match the block contents. Engine rules are checked by VerifyHeader.
+func validateHeader(block *Block) error {
+	if block.Version != BlockVersion {
+		return fmt.Errorf("unsupported block version %d", block.Version)
+	}
+	if !bytes.Equal(block.MerkleRoot, block.HashTransactions()) {
+		return fmt.Errorf("merkle root does not match block transactions")
+	}
+	if !bytes.Equal(block.Hash, block.BlockHeader.Hash()) {
+		return fmt.Errorf("block hash does not match header")
+	}
+	return nil
+}
+
+// validateCoinbase checks that the block has exactly one coinbase transaction,
+// that it commits to the block height, that its ID commits to its contents,
+// including the extranonce, and that it pays no more than the block reward
+// plus fees, the total verifyBlockTransactions returned
+func (bc *Blockchain) validateCoinbase(block *Block, fees float32) error {
+	var coinbase *Transaction
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() {
+			if coinbase != nil {
+				return fmt.Errorf("block has more than one coinbase transaction")
+			}
+			coinbase = tx
+		}
+	}
+
+	if coinbase == nil {
+		return fmt.Errorf("block has no coinbase transaction")
+	}
+	if !bytes.Equal(coinbase.ID, coinbase.Hash()) {
+		return fmt.Errorf("coinbase transaction ID does not match its contents")
+	}
+
+	height, err := coinbase.CoinbaseHeight()
+	if err != nil {
+		return err
+	}
+	if len(coinbase.Vin[0].Signature) > 8+MaxCoinbaseExtraData {
+		return fmt.Errorf("coinbase extra data exceeds %d bytes", MaxCoinbaseExtraData)
+	}
+	if height != block.Height {
+		return fmt.Errorf("coinbase commits to height %d, block is at height %d", height, block.Height)
+	}
+
+	paid := float32(0)
+	for _, out := range coinbase.Vout {
+		paid += out.Value
+	}
+	// Allow for float32 rounding when fees are summed in another order
+	if maxReward := bc.engine.BlockReward(block.Height) + fees; paid > maxReward+maxRewardRounding {
+		return fmt.Errorf("coinbase pays %f, block reward is at most %f", paid, maxReward)
+	}
+
+	return nil
+}
+
+// putBlock stores a block's header and body under its hash
+func putBlock(tx *bolt.Tx, block *Block) error {
+	err := tx.Bucket([]byte(headersBucket)).Put(block.Hash, block.BlockHeader.Serialize())
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(blocksBucket)).Put(block.Hash, block.SerializeBody())
+}
+
+// getHeader loads a block header by hash, or returns nil if it is unknown
+func getHeader(tx *bolt.Tx, hash []byte) *BlockHeader {
+	data := tx.Bucket([]byte(headersBucket)).Get(hash)
+	if data == nil {
+		return nil
+	}
+
+	header, err := DeserializeHeader(data)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return header
+}
+
+// genesisHash follows the headers back from tip to the first block and
+// returns its hash
+func genesisHash(tx *bolt.Tx, tip []byte) []byte {
+	hash := tip
+	for {
+		header := getHeader(tx, hash)
+		if header == nil || len(header.PrevBlockHash) == 0 {
+			return hash
+		}
+		hash = header.PrevBlockHash
+	}
+}
+
+// getBlock loads a full block by hash, or returns nil if it is unknown
+func getBlock(tx *bolt.Tx, hash []byte) *Block {
+	headerData := tx.Bucket([]byte(headersBucket)).Get(hash)
+	bodyData := tx.Bucket([]byte(blocksBucket)).Get(hash)
+	if headerData == nil || bodyData == nil {
+		return nil
+	}
+
+	return DeserializeBlock(headerData, bodyData)
+}
+
+// GetBlockHeader returns the header of the block with the given hash without loading its body
+func (bc *Blockchain) GetBlockHeader(hash []byte) (*BlockHeader, error) {
+	var header *BlockHeader
+
+	err := bc.DB.View(func(tx *bolt.Tx) error {
+		header = getHeader(tx, hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		return nil, errors.New("Block header is not found")
+	}
+
+	return header, nil
+}
+
 // FindTransaction finds a transaction by its ID
 func (bc *Blockchain) FindTransaction(ID []byte) (Transaction, error) {
 	bci := bc.Iterator()
@@ -210,36 +360,181 @@ func (bc *Blockchain) FindTransaction(ID []byte) (Transaction, error) {
 
 // SignTransaction signs inputs of a Transaction
 func (bc *Blockchain) SignTransaction(tx *Transaction, privKey *ecdsa.PrivateKey) {
+	prevTXs, err := bc.findPrevTransactions(tx, bc.GetPendingTransactions())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	tx.Sign(privKey, prevTXs)
+}
+
+// findPrevTransactions collects the transactions whose outputs tx spends.
+// Outputs created earlier in the same block can be spent by passing the
+// block's transactions as pending.
+func (bc *Blockchain) findPrevTransactions(tx *Transaction, pending []*Transaction) (map[string]Transaction, error) {
 	prevTXs := make(map[string]Transaction)
 
 	for _, vin := range tx.Vin {
-		prevTX, err := bc.FindTransaction(vin.Txid)
+		txID := hex.EncodeToString(vin.Txid)
+		if _, ok := prevTXs[txID]; ok {
+			continue
+		}
+
+		var prevTX *Transaction
+		for _, ptx := range pending {
+			if bytes.Equal(ptx.ID, vin.Txid) {
+				prevTX = ptx
+				break
+			}
+		}
+		if prevTX == nil {
+			found, err := bc.FindTransaction(vin.Txid)
+			if err != nil {
+				return nil, fmt.Errorf("input %s:%d references an unknown transaction", txID, vin.Vout)
+			}
+			prevTX = &found
+		}
+
+		prevTXs[txID] = *prevTX
+	}
+
+	for _, vin := range tx.Vin {
+		prevTX := prevTXs[hex.EncodeToString(vin.Txid)]
+		if vin.Vout < 0 || vin.Vout >= len(prevTX.Vout) {
+			return nil, fmt.Errorf("input %x:%d references a missing output", vin.Txid, vin.Vout)
+		}
+	}
+
+	return prevTXs, nil
+}
+
+// verifyBlockTransactions checks the IDs, outputs, lock times, input
+// signatures and token amounts of every transaction in a block, that every
+// declared fee is what the inputs leave over the outputs, and that no input is
+// already spent on the chain, and returns the total of the fees. No two
+// transactions in the block may spend the same output.
+func (bc *Blockchain) verifyBlockTransactions(block *Block) (float32, error) {
+	spent := make(map[string]bool)
+	fees := float32(0)
+	for _, tx := range block.Transactions {
+		if err := tx.CheckOutputs(); err != nil {
+			return 0, fmt.Errorf("transaction %x: %v", tx.ID, err)
+		}
+
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		// Outputs are spent by ID, which must commit to the content
+		if !bytes.Equal(tx.ID, tx.UnsignedID()) {
+			return 0, fmt.Errorf("transaction %x: ID does not match its content", tx.ID)
+		}
+
+		for _, vin := range tx.Vin {
+			outpoint := fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)
+			if spent[outpoint] {
+				return 0, fmt.Errorf("transaction %x: input %s is spent twice in the block", tx.ID, outpoint)
+			}
+			spent[outpoint] = true
+		}
+
+		if err := bc.CheckTransactionLocks(tx, block.Transactions, block.Height, block.Timestamp); err != nil {
+			return 0, fmt.Errorf("transaction %x: %v", tx.ID, err)
+		}
+
+		fees += tx.Fee
+
+		prevTXs, err := bc.findPrevTransactions(tx, block.Transactions)
 		if err != nil {
-			log.Panic(err)
+			return 0, fmt.Errorf("transaction %x: %v", tx.ID, err)
+		}
+
+		if !tx.Verify(prevTXs) {
+			return 0, fmt.Errorf("transaction %x has an invalid signature", tx.ID)
+		}
+
+		if err := tx.CheckTokens(prevTXs); err != nil {
+			return 0, fmt.Errorf("transaction %x: %v", tx.ID, err)
+		}
+
+		if err := tx.checkFee(prevTXs); err != nil {
+			return 0, fmt.Errorf("transaction %x: %v", tx.ID, err)
 		}
-		prevTXs[hex.EncodeToString(prevTX.ID)] = prevTX
 	}
 
-	tx.Sign(privKey, prevTXs)
+	// One pass over the chain covers the inputs of every transaction
+	if err := bc.checkUnspent(spent); err != nil {
+		return 0, err
+	}
+
+	return fees, nil
 }
 
-// VerifyTransaction verifies transaction input signatures
+// VerifyTransaction verifies transaction outputs, input signatures and token
+// amounts. Inputs may spend outputs of mempool transactions.
 func (bc *Blockchain) VerifyTransaction(tx *Transaction) bool {
+	return bc.CheckTransaction(tx, bc.GetPendingTransactions()) == nil
+}
+
+// CheckTransaction checks the transaction ID, outputs, input signatures and
+// token amounts against the chain and returns the first problem found. Inputs
+// may spend outputs of the pending transactions.
+func (bc *Blockchain) CheckTransaction(tx *Transaction, pending []*Transaction) error {
+	if err := tx.CheckOutputs(); err != nil {
+		return err
+	}
 	if tx.IsCoinbase() {
-		return true
+		return nil
+	}
+	if !bytes.Equal(tx.ID, tx.UnsignedID()) {
+		return fmt.Errorf("transaction ID does not match its content")
 	}
 
-	prevTXs := make(map[string]Transaction)
+	prevTXs, err := bc.findPrevTransactions(tx, pending)
+	if err != nil {
+		return err
+	}
+	if !tx.Verify(prevTXs) {
+		return fmt.Errorf("transaction has missing or invalid signatures")
+	}
 
+	return tx.CheckTokens(prevTXs)
+}
+
+// CheckUnspent checks that no input of tx is already spent on the chain
+func (bc *Blockchain) CheckUnspent(tx *Transaction) error {
+	inputs := make(map[string]bool)
 	for _, vin := range tx.Vin {
-		prevTX, err := bc.FindTransaction(vin.Txid)
-		if err != nil {
-			log.Panic(err)
+		inputs[fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)] = true
+	}
+
+	return bc.checkUnspent(inputs)
+}
+
+// checkUnspent checks that no chain transaction spends one of the outpoints,
+// given as txid:vout
+func (bc *Blockchain) checkUnspent(inputs map[string]bool) error {
+	bci := bc.Iterator()
+	for {
+		block := bci.Next()
+
+		for _, btx := range block.Transactions {
+			if btx.IsCoinbase() {
+				continue
+			}
+			for _, vin := range btx.Vin {
+				if inputs[fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)] {
+					return fmt.Errorf("input %x:%d is already spent by transaction %x", vin.Txid, vin.Vout, btx.ID)
+				}
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
 		}
-		prevTXs[hex.EncodeToString(prevTX.ID)] = prevTX
 	}
 
-	return tx.Verify(prevTXs)
+	return nil
 }
 
 // Iterator returns a BlockchainIterator
@@ -249,15 +544,39 @@ func (bc *Blockchain) Iterator() *BlockchainIterator {
 	return bci
 }
 
-// FindUnspentTransactions returns a list of transactions containing unspent outputs
-func (bc *Blockchain) FindUnspentTransactions(address string) []Transaction {
-	var unspentTXs []Transaction
+// UTXO is an unspent output together with the outpoint that references it
+type UTXO struct {
+	TxID   []byte
+	Vout   int
+	Output TXOutput
+}
+
+// forEachUnspentOutput calls fn for every unspent output paying to address
+func (bc *Blockchain) forEachUnspentOutput(address string, fn func(tx *Transaction, outIdx int)) {
+	bc.forEachUnspentOutputOf(map[string]bool{strings.ToLower(address): true}, fn)
+}
+
+// forEachUnspentOutputOf calls fn for every unspent output paying to one of
+// addresses, given in lowercase, in a single pass over the chain
+func (bc *Blockchain) forEachUnspentOutputOf(addresses map[string]bool, fn func(tx *Transaction, outIdx int)) {
 	spentTXOs := make(map[string][]int)
 	bci := bc.Iterator()
 
 	for {
 		block := bci.Next()
 
+		// Outputs can be spent later in the same block, so collect the
+		// block's spends before looking at its outputs. Multisig inputs carry
+		// no public key, so every input is recorded.
+		for _, tx := range block.Transactions {
+			if !tx.IsCoinbase() {
+				for _, in := range tx.Vin {
+					inTxID := hex.EncodeToString(in.Txid)
+					spentTXOs[inTxID] = append(spentTXOs[inTxID], in.Vout)
+				}
+			}
+		}
+
 		for _, tx := range block.Transactions {
 			txID := hex.EncodeToString(tx.ID)
 
@@ -272,19 +591,14 @@ func (bc *Blockchain) FindUnspentTransactions(address string) []Transaction {
 					}
 				}
 
-				// If the output address matches, it's spendable by the owner
-				if strings.EqualFold(out.Address, address) {
-					unspentTXs = append(unspentTXs, *tx)
+				// Data outputs are unspendable and never part of the UTXO set
+				if out.IsData() {
+					continue
 				}
-			}
 
-			// If this is not a coinbase transaction, collect all inputs that spent outputs
-			if !tx.IsCoinbase() {
-				for _, in := range tx.Vin {
-					if in.UsesKey(address) {
-						inTxID := hex.EncodeToString(in.Txid)
-						spentTXOs[inTxID] = append(spentTXOs[inTxID], in.Vout)
-					}
+				// If the output address matches, it's spendable by the owner
+				if addresses[strings.ToLower(out.Address)] {
+					fn(tx, outIdx)
 				}
 			}
 		}
@@ -293,31 +607,86 @@ func (bc *Blockchain) FindUnspentTransactions(address string) []Transaction {
 			break
 		}
 	}
-	return unspentTXs
 }
 
-// FindSpendableOutputs finds and returns unspent outputs to reference in inputs
-func (bc *Blockchain) FindSpendableOutputs(address string, amount float32) (float32, map[string][]int) {
-	unspentOutputs := make(map[string][]int)
-	unspentTXs := bc.FindUnspentTransactions(address)
-	accumulated := float32(0)
+// FindUnspentTransactions returns a list of transactions containing unspent outputs
+func (bc *Blockchain) FindUnspentTransactions(address string) []Transaction {
+	var unspentTXs []Transaction
+	seen := make(map[string]bool)
 
-Work:
-	for _, tx := range unspentTXs {
+	bc.forEachUnspentOutput(address, func(tx *Transaction, outIdx int) {
 		txID := hex.EncodeToString(tx.ID)
+		if !seen[txID] {
+			seen[txID] = true
+			unspentTXs = append(unspentTXs, *tx)
+		}
+	})
 
-		for outIdx, out := range tx.Vout {
-			if strings.EqualFold(out.Address, address) && accumulated < amount {
-				accumulated += out.Value
-				unspentOutputs[txID] = append(unspentOutputs[txID], outIdx)
+	return unspentTXs
+}
 
-				if accumulated >= amount {
-					break Work
-				}
+// FindUTXOs returns the unspent outputs paying to address
+func (bc *Blockchain) FindUTXOs(address string) []UTXO {
+	var utxos []UTXO
+
+	bc.forEachUnspentOutput(address, func(tx *Transaction, outIdx int) {
+		utxos = append(utxos, UTXO{TxID: tx.ID, Vout: outIdx, Output: tx.Vout[outIdx]})
+	})
+
+	return utxos
+}
+
+// FindPendingUTXOs returns the outputs paying to address that are not spent
+// once the mempool transactions confirm: confirmed outputs no pending
+// transaction spends, followed by the unspent outputs of pending transactions
+func (bc *Blockchain) FindPendingUTXOs(address string) []UTXO {
+	pending := bc.GetPendingTransactions()
+
+	spent := make(map[string]bool)
+	for _, tx := range pending {
+		for _, vin := range tx.Vin {
+			spent[fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)] = true
+		}
+	}
+
+	var utxos []UTXO
+	for _, utxo := range bc.FindUTXOs(address) {
+		if !spent[fmt.Sprintf("%x:%d", utxo.TxID, utxo.Vout)] {
+			utxos = append(utxos, utxo)
+		}
+	}
+	for _, tx := range pending {
+		for outIdx, out := range tx.Vout {
+			if out.IsData() || !strings.EqualFold(out.Address, address) || spent[fmt.Sprintf("%x:%d", tx.ID, outIdx)] {
+				continue
 			}
+			utxos = append(utxos, UTXO{TxID: tx.ID, Vout: outIdx, Output: out})
 		}
 	}
 
+	return utxos
+}
+
+// FindSpendableOutputs finds and returns unspent outputs to reference in inputs.
+// Outputs of mempool transactions can be spent, confirmed ones are used first.
+// Token outputs are left alone so paying DYP never burns tokens.
+func (bc *Blockchain) FindSpendableOutputs(address string, amount float32) (float32, map[string][]int) {
+	unspentOutputs := make(map[string][]int)
+	accumulated := float32(0)
+
+	for _, utxo := range bc.FindPendingUTXOs(address) {
+		if accumulated >= amount {
+			break
+		}
+		if utxo.Output.IsToken() {
+			continue
+		}
+
+		txID := hex.EncodeToString(utxo.TxID)
+		accumulated += utxo.Output.Value
+		unspentOutputs[txID] = append(unspentOutputs[txID], utxo.Vout)
+	}
+
 	return accumulated, unspentOutputs
 }
 
@@ -345,7 +714,19 @@ func (bc *Blockchain) GetGenesisAddress() string {
 	return genesisTx.Vout[0].Address
 }
 
-func dbExists() bool {
+// DataDir returns the directory holding the node's data files
+func DataDir() string {
+	return filepath.Dir(dbFile)
+}
+
+// SetDataDir moves the node's data files, the database of the active network
+// included, to dir
+func SetDataDir(dir string) {
+	dbFile = filepath.Join(dir, filepath.Base(dbFile))
+}
+
+// DBExists reports whether the active network's database file exists
+func DBExists() bool {
 	if _, err := os.Stat(dbFile); os.IsNotExist(err) {
 		fmt.Printf("Database file not found at: %s\n", dbFile)
 		return false
@@ -356,13 +737,13 @@ func dbExists() bool {
 // PrepareNewBlock creates a new block with the given transactions but doesn't mine it
 func (bc *Blockchain) PrepareNewBlock(transactions []*Transaction) *Block {
 	var lastHash []byte
-	var lastHeight int
+	var parent *BlockHeader
 
 	err := bc.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
-		lastHash = b.Get([]byte("l"))
-		lastBlock := DeserializeBlock(b.Get(lastHash))
-		lastHeight = lastBlock.Height
+		// Values read from bolt are only valid during the transaction
+		lastHash = append([]byte{}, b.Get([]byte("l"))...)
+		parent = getHeader(tx, lastHash)
 		return nil
 	})
 
@@ -370,10 +751,26 @@ func (bc *Blockchain) PrepareNewBlock(transactions []*Transaction) *Block {
 		log.Panic(err)
 	}
 
-	newBlock := NewBlock(transactions, lastHash, lastHeight+1)
+	newBlock := NewBlock(transactions, lastHash, parent.Height+1, bc.NextDifficulty())
+
+	// Blocks made within the same second must still move time forward
+	if mtp := MedianTimePast(bc, parent); newBlock.Timestamp <= mtp {
+		newBlock.Timestamp = mtp + 1
+	}
+
 	return newBlock
 }
 
+// NextDifficulty returns the difficulty required of the next block
+func (bc *Blockchain) NextDifficulty() int {
+	tip, err := bc.GetBlockHeader(bc.tip)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return bc.engine.CalcDifficulty(bc, tip)
+}
+
 // GetHeight returns the height of the blockchain
 func (bc *Blockchain) GetHeight() int {
 	var height int
@@ -381,9 +778,7 @@ func (bc *Blockchain) GetHeight() int {
 	err := bc.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 		lastHash := b.Get([]byte("l"))
-		lastBlockData := b.Get(lastHash)
-		lastBlock := DeserializeBlock(lastBlockData)
-		height = lastBlock.Height
+		height = getHeader(tx, lastHash).Height
 		return nil
 	})
 
@@ -401,8 +796,7 @@ func (bc *Blockchain) GetLastBlock() *Block {
 	err := bc.DB.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 		lastHash := b.Get([]byte("l"))
-		blockData := b.Get(lastHash)
-		lastBlock = DeserializeBlock(blockData)
+		lastBlock = getBlock(tx, lastHash)
 		return nil
 	})
 
@@ -413,94 +807,57 @@ func (bc *Blockchain) GetLastBlock() *Block {
 	return lastBlock
 }
 
-// AddTransaction adds a new transaction to the transaction pool
-func (bc *Blockchain) AddTransaction(tx *Transaction) {
-	bc.mempool = append(bc.mempool, tx)
-}
-
-// MineBlock mines a new block with the provided transactions
-func (bc *Blockchain) MineBlock(transactions []*Transaction) *Block {
-	fmt.Println("Mining block with transactions:", transactions)
-	var lastHash []byte
-	var lastHeight int
+// GetBlockByHeight returns the block of the chain at height
+func (bc *Blockchain) GetBlockByHeight(height int) (*Block, error) {
+	var block *Block
 
 	err := bc.DB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(blocksBucket))
-		lastHash = b.Get([]byte("l"))
-		lastBlock := DeserializeBlock(b.Get(lastHash))
-		lastHeight = lastBlock.Height
-		return nil
-	})
-
-	if err != nil {
-		log.Panic(err)
-	}
-
-	newBlock := NewBlock(transactions, lastHash, lastHeight+1)
-	newBlock.MineBlock() // Mine the block
-
-	err = bc.DB.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(blocksBucket))
-		err := b.Put(newBlock.Hash, newBlock.Serialize())
-		if err != nil {
-			return err
+		hash := tx.Bucket([]byte(blocksBucket)).Get([]byte("l"))
+		header := getHeader(tx, hash)
+		if height < 0 || height > header.Height {
+			return fmt.Errorf("no block at height %d", height)
 		}
 
-		err = b.Put([]byte("l"), newBlock.Hash)
-		if err != nil {
-			return err
+		for header.Height > height {
+			hash = header.PrevBlockHash
+			header = getHeader(tx, hash)
 		}
-
-		bc.tip = newBlock.Hash
-
+		block = getBlock(tx, hash)
 		return nil
 	})
 
-	if err != nil {
-		log.Panic(err)
-	}
-
-	return newBlock
+	return block, err
 }
 
-// GetPendingTransactions returns all transactions from the mempool
-func (bc *Blockchain) GetPendingTransactions() []*Transaction {
-	return bc.mempool
+// AddTransaction submits a transaction to the attached transaction pool,
+// which validates it before admission
+func (bc *Blockchain) AddTransaction(tx *Transaction) error {
+	if bc.txPool == nil {
+		return errNoTxPool
+	}
+	return bc.txPool.Add(tx)
 }
 
-// ClearTransactionsFromMempool removes the given transactions from the mempool
-func (bc *Blockchain) ClearTransactionsFromMempool(transactions []*Transaction) {
-
-	// Create a map of transaction IDs to remove for O(1) lookup
-	toRemove := make(map[string]bool)
-	for _, tx := range transactions {
-		if tx.ID == nil {
-			continue // Skip transactions without IDs
-		}
-		txID := hex.EncodeToString(tx.ID)
-		if txID == "" {
-			continue // Skip empty transaction IDs
-		}
-		toRemove[txID] = true
+// MineBlock mines a new block with the provided transactions and adds it to the chain
+func (bc *Blockchain) MineBlock(transactions []*Transaction) (*Block, error) {
+	newBlock := bc.PrepareNewBlock(transactions)
+	if err := bc.engine.Seal(bc, newBlock, nil); err != nil {
+		return nil, err
 	}
 
-	// Filter out the transactions that were mined
-	newMempool := make([]*Transaction, 0)
-	for _, tx := range bc.mempool {
-		if tx.ID == nil {
-			continue // Skip transactions without IDs
-		}
-		txID := hex.EncodeToString(tx.ID)
-		if txID == "" {
-			continue // Skip empty transaction IDs
-		}
-		if !toRemove[txID] {
-			newMempool = append(newMempool, tx)
-		} else {
-		}
+	if err := bc.AddBlock(newBlock, transactions); err != nil {
+		return nil, err
 	}
 
-	bc.mempool = newMempool
+	return newBlock, nil
+}
+
+// GetPendingTransactions returns all transactions from the mempool
+func (bc *Blockchain) GetPendingTransactions() []*Transaction {
+	if bc.txPool == nil {
+		return nil
+	}
+	return bc.txPool.Transactions()
 }
 
 // GetAllTransactions returns all transactions in the blockchain
@@ -614,15 +971,104 @@ func (bc *Blockchain) GetBalance(address string) float32 {
 	}
 
 	balance := float32(0)
-	unspentTXs := bc.FindUnspentTransactions(address)
+	for _, utxo := range bc.FindUTXOs(address) {
+		balance += utxo.Output.Value
+	}
+
+	return balance
+}
 
-	for _, tx := range unspentTXs {
+// Balance is the balance of an address including the mempool
+type Balance struct {
+	Confirmed       float32 // Unspent outputs in the chain
+	PendingIncoming float32 // Received by pending transactions
+	PendingOutgoing float32 // Sent by pending transactions, fees included
+	Spendable       float32 // Available once pending transactions confirm
+}
+
+// GetPendingBalance returns the confirmed balance of an address together with
+// what pending transactions add to and take from it. Each pending transaction
+// counts once, by what it takes from address minus what it pays back, so
+// change is not reported as incoming.
+func (bc *Blockchain) GetPendingBalance(address string) Balance {
+	return bc.GetPendingBalances([]string{address})[address]
+}
+
+// GetPendingBalances returns GetPendingBalance for each address, keyed as
+// given, scanning the chain once for all of them. Invalid addresses are left
+// out.
+func (bc *Blockchain) GetPendingBalances(addresses []string) map[string]Balance {
+	balances := make(map[string]Balance, len(addresses))
+	wanted := make(map[string]*Balance)
+	lookup := make(map[string]bool)
+	for _, address := range addresses {
+		if common.IsHexAddress(address) {
+			wanted[strings.ToLower(address)] = &Balance{}
+			lookup[strings.ToLower(address)] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return balances
+	}
+
+	// Outputs of the addresses that a pending transaction may spend
+	type ownedOutput struct {
+		address string
+		value   float32
+	}
+	owned := make(map[string]ownedOutput)
+	bc.forEachUnspentOutputOf(lookup, func(tx *Transaction, outIdx int) {
+		out := tx.Vout[outIdx]
+		address := strings.ToLower(out.Address)
+		wanted[address].Confirmed += out.Value
+		owned[fmt.Sprintf("%x:%d", tx.ID, outIdx)] = ownedOutput{address, out.Value}
+	})
+
+	pending := bc.GetPendingTransactions()
+	for _, tx := range pending {
+		for outIdx, out := range tx.Vout {
+			if address := strings.ToLower(out.Address); !out.IsData() && wanted[address] != nil {
+				owned[fmt.Sprintf("%x:%d", tx.ID, outIdx)] = ownedOutput{address, out.Value}
+			}
+		}
+	}
+
+	spent := make(map[string]bool)
+	for _, tx := range pending {
+		net := make(map[string]float32)
+		for _, vin := range tx.Vin {
+			outpoint := fmt.Sprintf("%x:%d", vin.Txid, vin.Vout)
+			spent[outpoint] = true
+			if out, ok := owned[outpoint]; ok {
+				net[out.address] -= out.value
+			}
+		}
 		for _, out := range tx.Vout {
-			if strings.EqualFold(out.Address, address) {
-				balance += out.Value
+			if address := strings.ToLower(out.Address); !out.IsData() && wanted[address] != nil {
+				net[address] += out.Value
+			}
+		}
+
+		for address, amount := range net {
+			if amount > 0 {
+				wanted[address].PendingIncoming += amount
+			} else {
+				wanted[address].PendingOutgoing -= amount
 			}
 		}
 	}
 
-	return balance
+	// What is left unspent once the pending transactions confirm
+	for outpoint, out := range owned {
+		if !spent[outpoint] {
+			wanted[out.address].Spendable += out.value
+		}
+	}
+
+	for _, address := range addresses {
+		if balance, ok := wanted[strings.ToLower(address)]; ok {
+			balances[address] = *balance
+		}
+	}
+	return balances
 }
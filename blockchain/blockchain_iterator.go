@@ -17,9 +17,7 @@ func (i *BlockchainIterator) Next() *Block {
 	var block *Block
 
 	err := i.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(blocksBucket))
-		encodedBlock := b.Get(i.currentHash)
-		block = DeserializeBlock(encodedBlock)
+		block = getBlock(tx, i.currentHash)
 
 		return nil
 	})
@@ -31,3 +29,21 @@ func (i *BlockchainIterator) Next() *Block {
 
 	return block
 }
+
+// NextHeader returns the next block header starting from the tip without loading block bodies
+func (i *BlockchainIterator) NextHeader() *BlockHeader {
+	var header *BlockHeader
+
+	err := i.db.View(func(tx *bolt.Tx) error {
+		header = getHeader(tx, i.currentHash)
+
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	i.currentHash = header.PrevBlockHash
+
+	return header
+}
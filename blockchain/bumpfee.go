@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BumpFee rebuilds a pending transaction paying fee instead of its current
+// fee. The increase comes out of the change output, which is dropped into the
+// fee when what remains would be dust. The result is unsigned and spends the
+// same outputs, so once the sender signs it the mempool replaces the original
+// with it.
+func BumpFee(orig *Transaction, fee float32, bc *Blockchain) (*Transaction, error) {
+	if orig.IsCoinbase() {
+		return nil, errors.New("coinbase transactions cannot be replaced")
+	}
+	if fee <= orig.Fee {
+		return nil, fmt.Errorf("new fee must be higher than the current fee of %f DYP", orig.Fee)
+	}
+
+	change := -1
+	for i, out := range orig.Vout {
+		if out.Multisig == nil && out.HTLC == nil && !out.IsData() && !out.IsToken() &&
+			common.IsHexAddress(out.Address) && common.HexToAddress(out.Address) == common.HexToAddress(orig.From) {
+			change = i
+		}
+	}
+	if change < 0 {
+		return nil, errors.New("transaction has no change output to take the higher fee from")
+	}
+
+	increase := fee - orig.Fee
+	if orig.Vout[change].Value < increase {
+		return nil, fmt.Errorf("change output of %f DYP cannot cover the %f DYP fee increase", orig.Vout[change].Value, increase)
+	}
+
+	var outputs []TXOutput
+	for i, out := range orig.Vout {
+		if i == change {
+			remaining := out.Value - increase
+			if remaining < bc.MempoolPolicy().DustThreshold {
+				fee += remaining // Not worth an output of its own
+				continue
+			}
+			out.Value = remaining
+		}
+		outputs = append(outputs, out)
+	}
+
+	inputs := make([]TXInput, len(orig.Vin))
+	for i, vin := range orig.Vin {
+		inputs[i] = TXInput{Txid: vin.Txid, Vout: vin.Vout, PubKey: vin.PubKey, Sequence: vin.Sequence}
+	}
+
+	tx := Transaction{
+		Vin:      inputs,
+		Vout:     outputs,
+		From:     orig.From,
+		To:       orig.To,
+		Amount:   orig.Amount,
+		Fee:      fee,
+		LockTime: orig.LockTime,
+		Issuance: orig.Issuance,
+	}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
@@ -0,0 +1,638 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// defaultCliqueEpoch is the number of blocks after which pending votes are
+	// discarded when the genesis does not set an epoch
+	defaultCliqueEpoch = 30000
+
+	// inmemorySnapshots is the number of recent signer snapshots kept in memory
+	inmemorySnapshots = 128
+
+	// snapshotCheckpointInterval is the number of blocks between snapshots
+	// that are kept for the lifetime of the node
+	snapshotCheckpointInterval = 1024
+
+	// wiggleTime is the per-signer delay an out-of-turn signer waits on top
+	// of the block time, so the in-turn signer's block usually wins
+	wiggleTime = 500 * time.Millisecond
+
+	// allowedFutureBlockTime is how far ahead of the local clock a header
+	// timestamp may be, to allow for clock drift between signers
+	allowedFutureBlockTime = 15 * time.Second
+
+	// nonceAuthVote and nonceDropVote are the header nonces voting to add or
+	// remove the address in the header's Vote field
+	nonceAuthVote = uint64(0xffffffffffffffff)
+	nonceDropVote = uint64(0)
+
+	// diffInTurn and diffNoTurn are the header bits of blocks sealed by the
+	// in-turn signer and by any other signer
+	diffInTurn = 2
+	diffNoTurn = 1
+)
+
+var (
+	// ErrUnauthorizedSigner is returned when a header is sealed by an address
+	// outside the signer set
+	ErrUnauthorizedSigner = errors.New("unauthorized signer")
+
+	// ErrRecentlySigned is returned when a signer seals again before enough
+	// other signers have had their turn
+	ErrRecentlySigned = errors.New("signer has signed recently, must wait for others")
+
+	// ErrNoSigningKey is returned by Seal when the node has no signing key
+	ErrNoSigningKey = errors.New("no signing key configured")
+)
+
+// CliqueConfig configures the proof-of-authority engine in the genesis
+type CliqueConfig struct {
+	Period  int      `json:"period"`  // Seconds between blocks
+	Epoch   int      `json:"epoch"`   // Blocks after which pending votes are reset
+	Signers []string `json:"signers"` // Initial signer set
+}
+
+// Validate checks the proof-of-authority configuration
+func (c *CliqueConfig) Validate() error {
+	if c.Period <= 0 {
+		return fmt.Errorf("genesis: clique period must be positive")
+	}
+	if c.Epoch < 0 {
+		return fmt.Errorf("genesis: clique epoch cannot be negative")
+	}
+	if len(c.Signers) == 0 {
+		return fmt.Errorf("genesis: clique needs at least one signer")
+	}
+
+	seen := make(map[common.Address]bool)
+	for i, signer := range c.Signers {
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("genesis: clique signer %d has invalid address %q", i, signer)
+		}
+		if seen[common.HexToAddress(signer)] {
+			return fmt.Errorf("genesis: clique signer %s is listed twice", signer)
+		}
+		seen[common.HexToAddress(signer)] = true
+	}
+
+	return nil
+}
+
+// Vote is a single vote cast by a signer to add or remove an address
+type Vote struct {
+	Signer    common.Address
+	Height    int
+	Address   common.Address
+	Authorize bool
+}
+
+// Tally is the running count of votes for one address
+type Tally struct {
+	Authorize bool
+	Votes     int
+}
+
+// Snapshot is the state of the signer set after a given block
+type Snapshot struct {
+	Height  int
+	Hash    []byte
+	Signers map[common.Address]struct{}
+	Recents map[int]common.Address // Signer of each recent block by height
+	Votes   []*Vote
+	Tally   map[common.Address]Tally
+}
+
+// newGenesisSnapshot creates the snapshot of the genesis signer set
+func newGenesisSnapshot(config *CliqueConfig, hash []byte) *Snapshot {
+	snap := &Snapshot{
+		Hash:    hash,
+		Signers: make(map[common.Address]struct{}),
+		Recents: make(map[int]common.Address),
+		Tally:   make(map[common.Address]Tally),
+	}
+	for _, signer := range config.Signers {
+		snap.Signers[common.HexToAddress(signer)] = struct{}{}
+	}
+	return snap
+}
+
+// copy returns a deep copy of the snapshot
+func (s *Snapshot) copy() *Snapshot {
+	cpy := &Snapshot{
+		Height:  s.Height,
+		Hash:    s.Hash,
+		Signers: make(map[common.Address]struct{}, len(s.Signers)),
+		Recents: make(map[int]common.Address, len(s.Recents)),
+		Votes:   make([]*Vote, len(s.Votes)),
+		Tally:   make(map[common.Address]Tally, len(s.Tally)),
+	}
+	for signer := range s.Signers {
+		cpy.Signers[signer] = struct{}{}
+	}
+	for height, signer := range s.Recents {
+		cpy.Recents[height] = signer
+	}
+	for address, tally := range s.Tally {
+		cpy.Tally[address] = tally
+	}
+	copy(cpy.Votes, s.Votes)
+
+	return cpy
+}
+
+// SignerList returns the signers in ascending order, which is the order in
+// which they take turns
+func (s *Snapshot) SignerList() []common.Address {
+	signers := make([]common.Address, 0, len(s.Signers))
+	for signer := range s.Signers {
+		signers = append(signers, signer)
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return bytes.Compare(signers[i][:], signers[j][:]) < 0
+	})
+	return signers
+}
+
+// inturn reports whether signer is the in-turn signer at height
+func (s *Snapshot) inturn(height int, signer common.Address) bool {
+	signers := s.SignerList()
+	return signers[height%len(signers)] == signer
+}
+
+// recentlySigned reports whether signer may not seal the block at height
+// because it sealed one of the last len(Signers)/2+1 blocks
+func (s *Snapshot) recentlySigned(height int, signer common.Address) bool {
+	limit := len(s.Signers)/2 + 1
+	for seen, recent := range s.Recents {
+		if recent == signer && seen > height-limit {
+			return true
+		}
+	}
+	return false
+}
+
+// validVote reports whether a vote would change the signer set
+func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
+	_, signer := s.Signers[address]
+	return (signer && !authorize) || (!signer && authorize)
+}
+
+// cast adds a vote to the tally
+func (s *Snapshot) cast(address common.Address, authorize bool) bool {
+	if !s.validVote(address, authorize) {
+		return false
+	}
+	if old, ok := s.Tally[address]; ok {
+		old.Votes++
+		s.Tally[address] = old
+	} else {
+		s.Tally[address] = Tally{Authorize: authorize, Votes: 1}
+	}
+	return true
+}
+
+// uncast removes a previously cast vote from the tally
+func (s *Snapshot) uncast(address common.Address, authorize bool) {
+	tally, ok := s.Tally[address]
+	if !ok || tally.Authorize != authorize {
+		return
+	}
+	if tally.Votes > 1 {
+		tally.Votes--
+		s.Tally[address] = tally
+	} else {
+		delete(s.Tally, address)
+	}
+}
+
+// apply returns the snapshot after the header sealed by signer
+func (s *Snapshot) apply(header *BlockHeader, signer common.Address, epoch int) (*Snapshot, error) {
+	snap := s.copy()
+	height := header.Height
+
+	// Pending votes are dropped at every epoch boundary
+	if height%epoch == 0 {
+		snap.Votes = nil
+		snap.Tally = make(map[common.Address]Tally)
+	}
+
+	// Let the oldest recent signer sign again
+	if limit := len(snap.Signers)/2 + 1; height >= limit {
+		delete(snap.Recents, height-limit)
+	}
+
+	if _, ok := snap.Signers[signer]; !ok {
+		return nil, ErrUnauthorizedSigner
+	}
+	for _, recent := range snap.Recents {
+		if recent == signer {
+			return nil, ErrRecentlySigned
+		}
+	}
+	snap.Recents[height] = signer
+
+	if len(header.Vote) != 0 {
+		address := common.BytesToAddress(header.Vote)
+		authorize := header.Nonce == nonceAuthVote
+
+		// A signer's new vote on an address replaces its previous one
+		for i, vote := range snap.Votes {
+			if vote.Signer == signer && vote.Address == address {
+				snap.uncast(vote.Address, vote.Authorize)
+				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+				break
+			}
+		}
+		if snap.cast(address, authorize) {
+			snap.Votes = append(snap.Votes, &Vote{
+				Signer:    signer,
+				Height:    height,
+				Address:   address,
+				Authorize: authorize,
+			})
+		}
+
+		// A majority of signers changes the signer set
+		if tally := snap.Tally[address]; tally.Votes > len(snap.Signers)/2 {
+			if tally.Authorize {
+				snap.Signers[address] = struct{}{}
+			} else {
+				delete(snap.Signers, address)
+
+				// The signer set shrank, so the recent window shrinks too
+				if limit := len(snap.Signers)/2 + 1; height >= limit {
+					delete(snap.Recents, height-limit)
+				}
+				// Votes of the removed signer no longer count
+				for i := 0; i < len(snap.Votes); i++ {
+					if snap.Votes[i].Signer == address {
+						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
+						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+						i--
+					}
+				}
+			}
+
+			// Votes about the address are settled
+			for i := 0; i < len(snap.Votes); i++ {
+				if snap.Votes[i].Address == address {
+					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+					i--
+				}
+			}
+			delete(snap.Tally, address)
+		}
+	}
+
+	snap.Height = height
+	snap.Hash = header.Hash()
+
+	return snap, nil
+}
+
+// CliqueEngine is a clique-style proof-of-authority consensus engine. A fixed
+// set of signers takes turns sealing blocks every Period seconds, and the set
+// changes through votes carried in block headers.
+type CliqueEngine struct {
+	genesis *Genesis
+	config  CliqueConfig
+
+	mu          sync.Mutex
+	signer      common.Address
+	signKey     *ecdsa.PrivateKey
+	proposals   map[common.Address]bool // Votes this node casts when sealing
+	recent      map[string]*Snapshot
+	recentOrder []string
+	checkpoints map[string]*Snapshot
+}
+
+// NewCliqueEngine creates a proof-of-authority engine for a network whose
+// genesis has a clique configuration
+func NewCliqueEngine(network *Network) *CliqueEngine {
+	config := *network.Genesis.Clique
+	if config.Epoch == 0 {
+		config.Epoch = defaultCliqueEpoch
+	}
+
+	return &CliqueEngine{
+		genesis:     network.Genesis,
+		config:      config,
+		proposals:   make(map[common.Address]bool),
+		recent:      make(map[string]*Snapshot),
+		checkpoints: make(map[string]*Snapshot),
+	}
+}
+
+// Authorize sets the key the node seals blocks with
+func (e *CliqueEngine) Authorize(key *ecdsa.PrivateKey) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.signKey = key
+	e.signer = crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// Signer returns the address the node seals blocks with
+func (e *CliqueEngine) Signer() common.Address {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.signer
+}
+
+// Period returns the target number of seconds between blocks
+func (e *CliqueEngine) Period() time.Duration {
+	return time.Duration(e.config.Period) * time.Second
+}
+
+// Propose makes the node vote to add (authorize) or remove an address from
+// the signer set in the blocks it seals until the vote passes
+func (e *CliqueEngine) Propose(address common.Address, authorize bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.proposals[address] = authorize
+}
+
+// Discard drops the node's proposal for an address
+func (e *CliqueEngine) Discard(address common.Address) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.proposals, address)
+}
+
+// Proposals returns the votes the node casts, keyed by address
+func (e *CliqueEngine) Proposals() map[common.Address]bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	proposals := make(map[common.Address]bool, len(e.proposals))
+	for address, authorize := range e.proposals {
+		proposals[address] = authorize
+	}
+	return proposals
+}
+
+// Snapshot returns the signer set and pending votes after header
+func (e *CliqueEngine) Snapshot(chain ChainReader, header *BlockHeader) (*Snapshot, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.snapshot(chain, header)
+}
+
+// snapshot replays headers from the closest known snapshot up to header. It
+// must be called with e.mu held.
+func (e *CliqueEngine) snapshot(chain ChainReader, header *BlockHeader) (*Snapshot, error) {
+	var (
+		headers []*BlockHeader
+		snap    *Snapshot
+	)
+
+	for {
+		key := fmt.Sprintf("%x", header.Hash())
+		if s, ok := e.recent[key]; ok {
+			snap = s
+			break
+		}
+		if s, ok := e.checkpoints[key]; ok {
+			snap = s
+			break
+		}
+		if header.Height == 0 {
+			snap = newGenesisSnapshot(&e.config, header.Hash())
+			break
+		}
+
+		headers = append(headers, header)
+		parent, err := chain.GetBlockHeader(header.PrevBlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("unknown ancestor %x", header.PrevBlockHash)
+		}
+		header = parent
+	}
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		signer, err := RecoverSigner(headers[i])
+		if err != nil {
+			return nil, err
+		}
+		if snap, err = snap.apply(headers[i], signer, e.config.Epoch); err != nil {
+			return nil, err
+		}
+		if snap.Height%snapshotCheckpointInterval == 0 {
+			e.checkpoints[fmt.Sprintf("%x", snap.Hash)] = snap
+		}
+	}
+
+	e.remember(snap)
+	return snap, nil
+}
+
+// remember caches a snapshot, evicting the oldest beyond inmemorySnapshots
+func (e *CliqueEngine) remember(snap *Snapshot) {
+	key := fmt.Sprintf("%x", snap.Hash)
+	if _, ok := e.recent[key]; ok {
+		return
+	}
+
+	e.recent[key] = snap
+	e.recentOrder = append(e.recentOrder, key)
+	if len(e.recentOrder) > inmemorySnapshots {
+		delete(e.recent, e.recentOrder[0])
+		e.recentOrder = e.recentOrder[1:]
+	}
+}
+
+// RecoverSigner returns the address that signed a header
+func RecoverSigner(header *BlockHeader) (common.Address, error) {
+	if len(header.Signature) != crypto.SignatureLength {
+		return common.Address{}, errors.New("header is not signed")
+	}
+
+	pubKey, err := crypto.SigToPub(header.SealHash(), header.Signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid header signature: %v", err)
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// calcDifficulty returns the bits of a block sealed by signer after snap
+func calcDifficulty(snap *Snapshot, signer common.Address) int {
+	if snap.inturn(snap.Height+1, signer) {
+		return diffInTurn
+	}
+	return diffNoTurn
+}
+
+// Seal votes on one of the node's proposals, waits until the block is due and
+// signs the header with the node's key. The genesis block is left unsigned.
+func (e *CliqueEngine) Seal(chain ChainReader, block *Block, stop <-chan struct{}) error {
+	if block.Height == 0 {
+		block.Hash = block.BlockHeader.Hash()
+		return nil
+	}
+
+	e.mu.Lock()
+	key, signer := e.signKey, e.signer
+	if key == nil {
+		e.mu.Unlock()
+		return ErrNoSigningKey
+	}
+
+	parent, err := chain.GetBlockHeader(block.PrevBlockHash)
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("unknown parent block %x", block.PrevBlockHash)
+	}
+	snap, err := e.snapshot(chain, parent)
+	if err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		e.mu.Unlock()
+		return ErrUnauthorizedSigner
+	}
+	if snap.recentlySigned(block.Height, signer) {
+		e.mu.Unlock()
+		return ErrRecentlySigned
+	}
+
+	// Cast one of the pending proposals that would still change the set
+	block.Vote, block.Nonce = nil, nonceDropVote
+	if block.Height%e.config.Epoch != 0 {
+		for address, authorize := range e.proposals {
+			if snap.validVote(address, authorize) {
+				block.Vote = address.Bytes()
+				if authorize {
+					block.Nonce = nonceAuthVote
+				}
+				break
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	block.Bits = calcDifficulty(snap, signer)
+	if minTime := parent.Timestamp + int64(e.config.Period); block.Timestamp < minTime {
+		block.Timestamp = minTime
+	}
+
+	// Out-of-turn signers hold back so the in-turn signer is usually first
+	delay := time.Until(time.Unix(block.Timestamp, 0))
+	if block.Bits == diffNoTurn {
+		wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
+		delay += time.Duration(rand.Int63n(int64(wiggle)))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return ErrSealStopped
+	case <-timer.C:
+	}
+
+	signature, err := crypto.Sign(block.SealHash(), key)
+	if err != nil {
+		return err
+	}
+	block.Signature = signature
+	block.Hash = block.BlockHeader.Hash()
+
+	return nil
+}
+
+// VerifyHeader checks the header's vote, timestamp, signer and turn
+func (e *CliqueEngine) VerifyHeader(chain ChainReader, header *BlockHeader) error {
+	if header.Height == 0 {
+		if len(header.Signature) != 0 || len(header.Vote) != 0 {
+			return errors.New("genesis header must not be signed")
+		}
+		return nil
+	}
+
+	// A signer stamping blocks ahead of time would make every later block
+	// wait for that time too
+	if time.Unix(header.Timestamp, 0).After(time.Now().Add(allowedFutureBlockTime)) {
+		return fmt.Errorf("block timestamp %d is in the future", header.Timestamp)
+	}
+
+	if len(header.Vote) != 0 {
+		if len(header.Vote) != common.AddressLength {
+			return errors.New("invalid vote address")
+		}
+		if header.Height%e.config.Epoch == 0 {
+			return errors.New("votes are not allowed on epoch blocks")
+		}
+	}
+	if header.Nonce != nonceAuthVote && header.Nonce != nonceDropVote {
+		return errors.New("header nonce must be an authorize or drop vote")
+	}
+
+	parent, err := chain.GetBlockHeader(header.PrevBlockHash)
+	if err != nil {
+		return fmt.Errorf("unknown parent block %x", header.PrevBlockHash)
+	}
+	if header.Timestamp < parent.Timestamp+int64(e.config.Period) {
+		return fmt.Errorf("block sealed less than %d seconds after its parent", e.config.Period)
+	}
+
+	signer, err := RecoverSigner(header)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	snap, err := e.snapshot(chain, parent)
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := snap.Signers[signer]; !ok {
+		return ErrUnauthorizedSigner
+	}
+	if snap.recentlySigned(header.Height, signer) {
+		return ErrRecentlySigned
+	}
+	if want := calcDifficulty(snap, signer); header.Bits != want {
+		return fmt.Errorf("wrong difficulty bits %d, want %d", header.Bits, want)
+	}
+
+	return nil
+}
+
+// CalcDifficulty returns the bits of the node's next block: diffInTurn when
+// its signer is in turn, otherwise diffNoTurn
+func (e *CliqueEngine) CalcDifficulty(chain ChainReader, parent *BlockHeader) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	snap, err := e.snapshot(chain, parent)
+	if err != nil || len(snap.Signers) == 0 {
+		return diffNoTurn
+	}
+	return calcDifficulty(snap, e.signer)
+}
+
+// BlockReward returns the network's block subsidy, paid to the sealer
+func (e *CliqueEngine) BlockReward(height int) float32 {
+	return e.genesis.Subsidy(height)
+}
@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCliqueRejectsFutureHeaders(t *testing.T) {
+	genesis := *RegTest.Genesis
+	genesis.Clique = &CliqueConfig{Period: 5, Signers: []string{devAddress}}
+	engine := NewCliqueEngine(&Network{Name: "clique", Genesis: &genesis})
+
+	header := &BlockHeader{
+		Version:   BlockVersion,
+		Height:    1,
+		Timestamp: time.Now().Add(time.Hour).Unix(),
+	}
+	err := engine.VerifyHeader(nil, header)
+	if err == nil || !strings.Contains(err.Error(), "in the future") {
+		t.Fatalf("header an hour ahead verified with %v, want it rejected as in the future", err)
+	}
+}
+
+// newSignerChain creates the chain of a proof-of-authority node sealing with
+// privateKeyHex in a temporary directory
+func newSignerChain(t *testing.T, network *Network, privateKeyHex string) *Blockchain {
+	t.Helper()
+
+	prevNetwork, prevDBFile := activeNetwork, dbFile
+	activeNetwork = network
+	dbFile = filepath.Join(t.TempDir(), "blockchain.db")
+
+	bc := CreateBlockchain(network.Genesis)
+	t.Cleanup(func() {
+		bc.DB.Close()
+		activeNetwork, dbFile = prevNetwork, prevDBFile
+	})
+
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.Engine().(*CliqueEngine).Authorize(wallet.PrivateKey)
+	return bc
+}
+
+func TestCliqueSignersTakeTurns(t *testing.T) {
+	genesis := *RegTest.Genesis
+	genesis.Timestamp = time.Now().Add(-time.Hour).Unix()
+	genesis.Clique = &CliqueConfig{Period: 5, Signers: []string{devAddress, recipient}}
+	network := &Network{Name: "clique", Genesis: &genesis}
+
+	// Signers are in turn in the order of their addresses
+	chains := []*Blockchain{newSignerChain(t, network, recipientKey), newSignerChain(t, network, devKey)}
+	signers := []string{recipient, devAddress}
+
+	for height := 1; height <= 4; height++ {
+		producer, follower := chains[height%2], chains[(height+1)%2]
+
+		// The follower sealed the previous block and has to wait its turn
+		if height > 1 {
+			early := follower.PrepareNewBlock([]*Transaction{NewCoinbaseTx(signers[(height+1)%2], "test", height, 0)})
+			if err := follower.Engine().Seal(follower, early, nil); !errors.Is(err, ErrRecentlySigned) {
+				t.Fatalf("block %d: signer of the previous block sealed with %v", height, err)
+			}
+		}
+
+		parent := producer.GetLastBlock()
+		coinbase := NewCoinbaseTx(signers[height%2], "test", height, producer.Engine().BlockReward(height))
+		block := producer.PrepareNewBlock([]*Transaction{coinbase})
+		block.Timestamp = parent.Timestamp + 5 // Already due, so sealing does not wait
+		if err := producer.Engine().Seal(producer, block, nil); err != nil {
+			t.Fatalf("block %d: %v", height, err)
+		}
+		if err := producer.AddBlock(block, block.Transactions); err != nil {
+			t.Fatalf("block %d: %v", height, err)
+		}
+
+		// The other signer imports the block as a peer receives it
+		imported, err := DecodeBlock(block.BlockHeader.Serialize(), block.SerializeBody())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := follower.AddBlock(imported, imported.Transactions); err != nil {
+			t.Fatalf("block %d sealed by %s not imported: %v", height, signers[height%2], err)
+		}
+	}
+
+	for _, bc := range chains {
+		if tip := bc.GetLastBlock(); tip.Height != 4 || !bytes.Equal(tip.Hash, chains[0].GetLastBlock().Hash) {
+			t.Fatalf("chains diverged: tip %x at height %d", tip.Hash, tip.Height)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package blockchain
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrSealStopped is returned by Seal when the stop channel is closed
+	ErrSealStopped = errors.New("sealing stopped")
+
+	// ErrNonceSpaceExhausted is returned by Seal when no nonce satisfies the
+	// target; the caller must change the block (e.g. roll the extranonce)
+	ErrNonceSpaceExhausted = errors.New("nonce space exhausted")
+)
+
+// ChainReader gives consensus engines access to the headers of the local chain
+type ChainReader interface {
+	GetBlockHeader(hash []byte) (*BlockHeader, error)
+}
+
+// ConsensusEngine decides how blocks are produced and which headers are valid
+type ConsensusEngine interface {
+	// Seal completes the block header so it satisfies the engine's rules and
+	// sets the block hash. It gives up with ErrSealStopped once stop is closed.
+	Seal(chain ChainReader, block *Block, stop <-chan struct{}) error
+
+	// VerifyHeader checks that a header satisfies the engine's rules
+	VerifyHeader(chain ChainReader, header *BlockHeader) error
+
+	// CalcDifficulty returns the difficulty of the block following parent
+	CalcDifficulty(chain ChainReader, parent *BlockHeader) int
+
+	// BlockReward returns the subsidy paid to the producer of the block at height
+	BlockReward(height int) float32
+}
+
+// NewEngine creates the consensus engine configured for a network: proof of
+// authority when the genesis has a clique section, proof of work otherwise
+func NewEngine(network *Network) ConsensusEngine {
+	if network.Genesis.Clique != nil {
+		return NewCliqueEngine(network)
+	}
+	return NewPowEngine(network)
+}
+
+// MedianTimePast returns the median timestamp of parent and the blocks before
+// it, up to MedianTimeBlocks of them
+func MedianTimePast(chain ChainReader, parent *BlockHeader) int64 {
+	timestamps := make([]int64, 0, MedianTimeBlocks)
+	for header := parent; len(timestamps) < MedianTimeBlocks; {
+		timestamps = append(timestamps, header.Timestamp)
+		if header.Height == 0 {
+			break
+		}
+		prev, err := chain.GetBlockHeader(header.PrevBlockHash)
+		if err != nil {
+			break
+		}
+		header = prev
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
@@ -0,0 +1,195 @@
+package blockchain
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The regtest genesis funds the address of devKey
+const (
+	devKey     = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	devAddress = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	recipient  = "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+	miner      = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+)
+
+// newTestChain creates a regtest chain in a temporary directory
+func newTestChain(t *testing.T) *Blockchain {
+	t.Helper()
+
+	prevNetwork, prevDBFile := activeNetwork, dbFile
+	activeNetwork = RegTest
+	dbFile = filepath.Join(t.TempDir(), "blockchain.db")
+
+	bc := CreateBlockchain(RegTest.Genesis)
+	t.Cleanup(func() {
+		bc.DB.Close()
+		activeNetwork, dbFile = prevNetwork, prevDBFile
+	})
+
+	return bc
+}
+
+// mineWith mines a block holding txs whose coinbase claims the block reward
+// plus claimedFees
+func mineWith(bc *Blockchain, txs []*Transaction, claimedFees float32) error {
+	height := bc.GetHeight() + 1
+	coinbase := NewCoinbaseTx(miner, "test", height, bc.engine.BlockReward(height)+claimedFees)
+	_, err := bc.MineBlock(append(txs, coinbase))
+	return err
+}
+
+// resign recomputes the ID and signatures of tx after it was modified, as
+// the sender of a forged transaction can
+func resign(t *testing.T, bc *Blockchain, tx *Transaction) {
+	t.Helper()
+
+	wallet, err := NewWalletFromPrivateKey(devKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tx.Vin {
+		tx.Vin[i].Signature = nil
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(tx, wallet.PrivateKey)
+}
+
+func expectRejected(t *testing.T, err error, reason string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("block accepted, expected it to be rejected with %q", reason)
+	}
+	if !strings.Contains(err.Error(), reason) {
+		t.Fatalf("block rejected with %q, expected %q", err, reason)
+	}
+}
+
+func TestAddBlockAcceptsPaidFees(t *testing.T) {
+	bc := newTestChain(t)
+
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0.5, TxOptions{}, bc)
+	if err := mineWith(bc, []*Transaction{tx}, tx.Fee); err != nil {
+		t.Fatal(err)
+	}
+
+	if balance := bc.GetBalance(recipient); balance != 10 {
+		t.Errorf("recipient balance is %f, want 10", balance)
+	}
+	if balance, want := bc.GetBalance(miner), bc.engine.BlockReward(1)+0.5; balance != want {
+		t.Errorf("miner balance is %f, want %f", balance, want)
+	}
+}
+
+func TestAddBlockRejectsUnpaidDeclaredFee(t *testing.T) {
+	bc := newTestChain(t)
+
+	// The inputs pay a fee of 0.5, the transaction claims 1000
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0.5, TxOptions{}, bc)
+	tx.Fee = 1000
+	resign(t, bc, tx)
+
+	expectRejected(t, mineWith(bc, []*Transaction{tx}, tx.Fee), "does not match inputs minus outputs")
+}
+
+func TestAddBlockRejectsOutputsAboveInputs(t *testing.T) {
+	bc := newTestChain(t)
+
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0, TxOptions{}, bc)
+	tx.Vout[0].Value += 5000
+	resign(t, bc, tx)
+
+	expectRejected(t, mineWith(bc, []*Transaction{tx}, 0), "exceed inputs")
+}
+
+func TestAddBlockRejectsNegativeOutputs(t *testing.T) {
+	bc := newTestChain(t)
+
+	// A negative output would let the other outputs exceed the inputs
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0, TxOptions{}, bc)
+	tx.Vout = append(tx.Vout, *NewTXOutput(-5000, devAddress))
+	tx.Vout[0].Value += 5000
+	resign(t, bc, tx)
+
+	expectRejected(t, mineWith(bc, []*Transaction{tx}, 0), "invalid value")
+}
+
+func TestAddBlockRejectsSpentInputs(t *testing.T) {
+	bc := newTestChain(t)
+
+	// Both spend the genesis allocation
+	first := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0, TxOptions{}, bc)
+	second := NewUTXOTransaction(devKey, devAddress, miner, 20, 0, TxOptions{}, bc)
+
+	if err := mineWith(bc, []*Transaction{first}, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectRejected(t, mineWith(bc, []*Transaction{second}, 0), "already spent")
+}
+
+func TestAddBlockRejectsMismatchedTransactionID(t *testing.T) {
+	bc := newTestChain(t)
+
+	// Signatures cover the content, not the ID, so they stay valid
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0.5, TxOptions{}, bc)
+	tx.ID = bytes.Repeat([]byte{0xab}, 32)
+
+	expectRejected(t, mineWith(bc, []*Transaction{tx}, tx.Fee), "ID does not match")
+}
+
+func TestAddBlockRejectsCoinbaseAboveReward(t *testing.T) {
+	bc := newTestChain(t)
+
+	tx := NewUTXOTransaction(devKey, devAddress, recipient, 10, 0.5, TxOptions{}, bc)
+
+	expectRejected(t, mineWith(bc, []*Transaction{tx}, tx.Fee+1), "block reward is at most")
+}
+
+func TestPowVerifiesTimestampBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp func(bc *Blockchain) int64
+		reason    string // Empty when the block is accepted
+	}{
+		{"at the median time past", func(bc *Blockchain) int64 {
+			return bc.GetLastBlock().Timestamp
+		}, "median time past"},
+		{"before the median time past", func(bc *Blockchain) int64 {
+			return RegTest.Genesis.Timestamp
+		}, "median time past"},
+		{"beyond the future limit", func(bc *Blockchain) int64 {
+			return time.Now().Add(MaxTimeDeviation + time.Minute).Unix()
+		}, "too far in the future"},
+		{"within the future limit", func(bc *Blockchain) int64 {
+			return time.Now().Add(MaxTimeDeviation - time.Minute).Unix()
+		}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bc := newTestChain(t)
+			if err := mineWith(bc, nil, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			coinbase := NewCoinbaseTx(miner, "test", 2, bc.engine.BlockReward(2))
+			block := bc.PrepareNewBlock([]*Transaction{coinbase})
+			block.Timestamp = test.timestamp(bc)
+			if err := bc.engine.Seal(bc, block, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			err := bc.AddBlock(block, block.Transactions)
+			if test.reason == "" {
+				if err != nil {
+					t.Fatalf("block rejected: %v", err)
+				}
+				return
+			}
+			expectRejected(t, err, test.reason)
+		})
+	}
+}
@@ -24,8 +24,26 @@ const (
 	// MaxDifficulty is the maximum difficulty allowed
 	MaxDifficulty = 64
 
-	// MaxTimeDeviation is the maximum time difference allowed between blocks
+	// MaxTimeDeviation is how far a block timestamp may run ahead of the
+	// local clock
 	MaxTimeDeviation = 2 * time.Hour
+
+	// MedianTimeBlocks is the number of blocks whose median timestamp a new
+	// block must exceed
+	MedianTimeBlocks = 11
+
+	// MaxCoinbaseExtraData is the maximum size of miner data in a coinbase input
+	MaxCoinbaseExtraData = 64
+
+	// MaxDataOutputSize is the maximum payload of a data output in bytes
+	MaxDataOutputSize = 80
+
+	// MaxTokenMetadataSize is the maximum size of a token's issuance metadata
+	MaxTokenMetadataSize = 256
+
+	// maxRewardRounding is the float32 rounding tolerated when checking the
+	// coinbase against the block reward plus fees
+	maxRewardRounding = 0.0001
 )
 
 // CalculateNextDifficulty calculates the next difficulty based on the time taken to mine the previous blocks
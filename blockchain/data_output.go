@@ -0,0 +1,120 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// NewDataOutput creates a provably unspendable output carrying data
+func NewDataOutput(data []byte) *TXOutput {
+	return &TXOutput{Value: 0, Data: data}
+}
+
+// IsData reports whether the output is a data output
+func (out *TXOutput) IsData() bool {
+	return len(out.Data) > 0
+}
+
+// checkData checks that a data output carries nothing but its payload
+func (out *TXOutput) checkData() error {
+	if len(out.Data) > MaxDataOutputSize {
+		return fmt.Errorf("data output exceeds %d bytes", MaxDataOutputSize)
+	}
+	if out.Value != 0 || out.Address != "" || out.Multisig != nil || out.HTLC != nil || out.IsToken() {
+		return errors.New("data output cannot carry value, tokens or a lock")
+	}
+	return nil
+}
+
+// NewDataTransaction creates a transaction anchoring data on chain. The
+// sender only pays the fee; any change goes back to from.
+func NewDataTransaction(privateKeyHex, from string, data []byte, fee float32, bc *Blockchain) (*Transaction, error) {
+	if len(data) == 0 || len(data) > MaxDataOutputSize {
+		return nil, fmt.Errorf("data must be between 1 and %d bytes", MaxDataOutputSize)
+	}
+	if fee <= 0 {
+		return nil, errors.New("a fee is required to anchor data")
+	}
+	if !common.IsHexAddress(from) {
+		return nil, errors.New("invalid address format")
+	}
+
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, validOutputs := bc.FindSpendableOutputs(from, fee)
+	if acc < fee {
+		return nil, errors.New("not enough funds to cover the fee")
+	}
+
+	inputs, err := spendInputs(validOutputs, crypto.FromECDSAPub(&wallet.PrivateKey.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []TXOutput{*NewDataOutput(data)}
+	if acc > fee {
+		outputs = append(outputs, *NewTXOutput(acc-fee, from))
+	}
+
+	tx := Transaction{
+		Vin:  inputs,
+		Vout: outputs,
+		From: from,
+		To:   from,
+		Fee:  fee,
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(&tx, wallet.PrivateKey)
+
+	return &tx, nil
+}
+
+// FindDataOutput returns the earliest transaction anchoring data and the
+// block that includes it. A pending transaction is returned with a nil block.
+func (bc *Blockchain) FindDataOutput(data []byte) (*Transaction, *Block, error) {
+	hasData := func(tx *Transaction) bool {
+		for _, out := range tx.Vout {
+			if out.IsData() && bytes.Equal(out.Data, data) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var foundTx *Transaction
+	var foundBlock *Block
+
+	// Walk the whole chain so the oldest anchor wins
+	bci := bc.Iterator()
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			if hasData(tx) {
+				foundTx, foundBlock = tx, block
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+	if foundTx != nil {
+		return foundTx, foundBlock, nil
+	}
+
+	for _, tx := range bc.GetPendingTransactions() {
+		if hasData(tx) {
+			return tx, nil, nil
+		}
+	}
+
+	return nil, nil, errors.New("Data is not anchored")
+}
@@ -0,0 +1,302 @@
+package blockchain
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HD wallet parameters
+const (
+	// HardenedOffset is added to an index to derive a hardened child
+	HardenedOffset = 0x80000000
+
+	// ExternalChain holds receiving addresses, ChangeChain holds change
+	ExternalChain = 0
+	ChangeChain   = 1
+
+	// DefaultGapLimit is the number of consecutive unused addresses after
+	// which discovery stops scanning a chain, MaxGapLimit the largest
+	// accepted
+	DefaultGapLimit = 20
+	MaxGapLimit     = 100
+
+	// MaxDiscoveredAddresses bounds the addresses one discovery reports
+	// balances for
+	MaxDiscoveredAddresses = 1000
+
+	// mnemonicEntropyBits gives 12 word mnemonics
+	mnemonicEntropyBits = 128
+
+	// coinType is the BIP44 coin type, the Ethereum one so that keys and
+	// addresses match Ethereum wallets
+	coinType = 60
+)
+
+// NewMnemonic generates a random BIP39 mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropyBits)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate entropy: %v", err)
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// extendedKey is a BIP32 private key with its chain code
+type extendedKey struct {
+	key       []byte // 32 byte private key
+	chainCode []byte
+}
+
+// child derives the child key at index, hardened from HardenedOffset on
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= HardenedOffset {
+		data = append([]byte{0}, k.key...)
+	} else {
+		privateKey, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&privateKey.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// The child key is IL + k mod n, unusable when IL >= n or the sum is 0
+	n := crypto.S256().Params().N
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+	childKey := tweak.Add(tweak, new(big.Int).SetBytes(k.key))
+	childKey.Mod(childKey, n)
+	if childKey.Sign() == 0 {
+		return nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+
+	return &extendedKey{key: childKey.FillBytes(make([]byte, 32)), chainCode: sum[32:]}, nil
+}
+
+// HDWallet derives wallets from a BIP39 mnemonic following BIP32 and BIP44
+type HDWallet struct {
+	master *extendedKey
+}
+
+// NewHDWalletFromMnemonic recovers the HD wallet of a mnemonic. The passphrase
+// is the optional BIP39 passphrase, empty for most wallets.
+func NewHDWalletFromMnemonic(mnemonic, passphrase string) (*HDWallet, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic: unknown word, wrong length or bad checksum")
+	}
+	return newHDWalletFromSeed(bip39.NewSeed(mnemonic, passphrase))
+}
+
+// newHDWalletFromSeed derives the BIP32 master key of a seed
+func newHDWalletFromSeed(seed []byte) (*HDWallet, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	master := &extendedKey{key: sum[:32], chainCode: sum[32:]}
+	if _, err := crypto.ToECDSA(master.key); err != nil {
+		return nil, errors.New("seed gives an invalid master key")
+	}
+
+	return &HDWallet{master: master}, nil
+}
+
+// ParseDerivationPath parses a path such as m/44'/60'/0'/0/0. Hardened
+// indexes are marked with ' or h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) < 2 || parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q must start with m/", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = HardenedOffset
+			part = part[:len(part)-1]
+		}
+
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", part, path)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+
+	return indexes, nil
+}
+
+// FormatDerivationPath formats indexes the way ParseDerivationPath reads them
+func FormatDerivationPath(indexes []uint32) string {
+	var sb strings.Builder
+	sb.WriteString("m")
+	for _, index := range indexes {
+		if index >= HardenedOffset {
+			fmt.Fprintf(&sb, "/%d'", index-HardenedOffset)
+		} else {
+			fmt.Fprintf(&sb, "/%d", index)
+		}
+	}
+	return sb.String()
+}
+
+// AccountPath returns the BIP44 path of an account, m/44'/60'/account'
+func AccountPath(account uint32) []uint32 {
+	return []uint32{44 + HardenedOffset, coinType + HardenedOffset, account + HardenedOffset}
+}
+
+// AddressPath returns the BIP44 path of an address, m/44'/60'/account'/chain/index
+func AddressPath(account, chain, index uint32) []uint32 {
+	return append(AccountPath(account), chain, index)
+}
+
+// Derive returns the wallet at a derivation path
+func (hw *HDWallet) Derive(path []uint32) (*Wallet, error) {
+	key := hw.master
+	for _, index := range path {
+		var err error
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewWalletFromPrivateKey(fmt.Sprintf("%x", key.key))
+}
+
+// DeriveAddress returns the wallet at index of a chain of an account
+func (hw *HDWallet) DeriveAddress(account, chain, index uint32) (*Wallet, error) {
+	return hw.Derive(AddressPath(account, chain, index))
+}
+
+// DiscoveredAddress is a used address found by Discover
+type DiscoveredAddress struct {
+	Path    string
+	Chain   uint32
+	Index   uint32
+	Address string
+	Balance Balance
+}
+
+// Discover scans the external and change chains of an account for addresses
+// used in the chain or the mempool, stopping each chain after gapLimit
+// consecutive unused addresses, and returns them with their balances
+func (hw *HDWallet) Discover(bc *Blockchain, account uint32, gapLimit int) ([]DiscoveredAddress, error) {
+	accountKey, err := hw.ExtendedPublicKey(AccountPath(account))
+	if err != nil {
+		return nil, err
+	}
+	return DiscoverAccount(bc, accountKey, gapLimit)
+}
+
+// DiscoverAccount is Discover for the extended public key of an account, so
+// the addresses of a wallet can be found without its mnemonic
+func DiscoverAccount(bc *Blockchain, accountKey *ExtendedPublicKey, gapLimit int) ([]DiscoveredAddress, error) {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	if gapLimit < 0 || gapLimit > MaxGapLimit {
+		return nil, fmt.Errorf("gap limit must be between 1 and %d", MaxGapLimit)
+	}
+	account, err := accountKey.Account()
+	if err != nil {
+		return nil, err
+	}
+	used := bc.usedAddresses()
+
+	var found []DiscoveredAddress
+	for _, chain := range []uint32{ExternalChain, ChangeChain} {
+		chainKey, err := accountKey.Child(chain)
+		if err != nil {
+			return nil, err
+		}
+
+		gap := 0
+		for index := uint32(0); gap < gapLimit; index++ {
+			key, err := chainKey.Child(index)
+			if err != nil {
+				return nil, err
+			}
+			address, err := key.Address()
+			if err != nil {
+				return nil, err
+			}
+			if !used[strings.ToLower(address)] {
+				gap++
+				continue
+			}
+			gap = 0
+
+			if len(found) == MaxDiscoveredAddresses {
+				return nil, fmt.Errorf("account has more than %d used addresses", MaxDiscoveredAddresses)
+			}
+			found = append(found, DiscoveredAddress{
+				Path:    FormatDerivationPath(AddressPath(account, chain, index)),
+				Chain:   chain,
+				Index:   index,
+				Address: address,
+			})
+		}
+	}
+
+	// One pass over the chain gives every balance
+	addresses := make([]string, len(found))
+	for i, addr := range found {
+		addresses[i] = addr.Address
+	}
+	balances := bc.GetPendingBalances(addresses)
+	for i := range found {
+		found[i].Balance = balances[found[i].Address]
+	}
+
+	return found, nil
+}
+
+// usedAddresses returns the lowercase addresses that sent or received a
+// transaction in the chain or the mempool
+func (bc *Blockchain) usedAddresses() map[string]bool {
+	used := make(map[string]bool)
+	record := func(tx *Transaction) {
+		if tx.From != "" {
+			used[strings.ToLower(tx.From)] = true
+		}
+		for _, out := range tx.Vout {
+			if out.Address != "" {
+				used[strings.ToLower(out.Address)] = true
+			}
+		}
+	}
+
+	bci := bc.Iterator()
+	for {
+		block := bci.Next()
+		for _, tx := range block.Transactions {
+			record(tx)
+		}
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+	for _, tx := range bc.GetPendingTransactions() {
+		record(tx)
+	}
+
+	return used
+}
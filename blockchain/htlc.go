@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Hash functions an HTLC can be locked with
+const (
+	HTLCHashSHA256    = "sha256"
+	HTLCHashKeccak256 = "keccak256"
+)
+
+// HTLCLock locks an output to Recipient revealing the preimage of Hash, or to
+// Sender once the chain reaches the Timeout height
+type HTLCLock struct {
+	HashType  string
+	Hash      []byte
+	Recipient string
+	Sender    string
+	Timeout   int
+}
+
+// NewHTLCLock validates and normalizes a hash time lock
+func NewHTLCLock(hashType string, hash []byte, recipient, sender string, timeout int) (*HTLCLock, error) {
+	if !common.IsHexAddress(recipient) || !common.IsHexAddress(sender) {
+		return nil, errors.New("invalid HTLC recipient or sender address")
+	}
+
+	lock := &HTLCLock{
+		HashType:  hashType,
+		Hash:      hash,
+		Recipient: common.HexToAddress(recipient).Hex(),
+		Sender:    common.HexToAddress(sender).Hex(),
+		Timeout:   timeout,
+	}
+	if err := lock.Validate(); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Validate checks that a lock read from a transaction is well formed
+func (l *HTLCLock) Validate() error {
+	if l.HashType != HTLCHashSHA256 && l.HashType != HTLCHashKeccak256 {
+		return fmt.Errorf("unsupported HTLC hash type %q", l.HashType)
+	}
+	if len(l.Hash) != 32 {
+		return errors.New("HTLC hash must be 32 bytes")
+	}
+	if l.Timeout <= 0 || l.Timeout >= LockTimeThreshold {
+		return fmt.Errorf("HTLC timeout must be a block height between 1 and %d", LockTimeThreshold-1)
+	}
+	if !common.IsHexAddress(l.Recipient) || !common.IsHexAddress(l.Sender) {
+		return errors.New("invalid HTLC recipient or sender address")
+	}
+	return nil
+}
+
+// Address returns the address that outputs with this lock pay to
+func (l *HTLCLock) Address() string {
+	hash := crypto.Keccak256(
+		[]byte(l.HashType),
+		l.Hash,
+		common.HexToAddress(l.Recipient).Bytes(),
+		common.HexToAddress(l.Sender).Bytes(),
+		IntToHex(int64(l.Timeout)),
+	)
+	return common.BytesToAddress(hash[12:]).Hex()
+}
+
+// Matches reports whether preimage hashes to the lock's hash
+func (l *HTLCLock) Matches(preimage []byte) bool {
+	return bytes.Equal(hashPreimage(l.HashType, preimage), l.Hash)
+}
+
+// spender returns the address that must sign an input spending the lock. A
+// preimage selects the redeem path; without one the sender refunds, which
+// requires the transaction to be time locked at or past the timeout.
+func (l *HTLCLock) spender(preimage []byte, lockTime int64) (string, error) {
+	if len(preimage) > 0 {
+		if !l.Matches(preimage) {
+			return "", errors.New("HTLC preimage does not match")
+		}
+		return l.Recipient, nil
+	}
+
+	if lockTime < int64(l.Timeout) || lockTime >= LockTimeThreshold {
+		return "", fmt.Errorf("HTLC refund must be locked until block %d", l.Timeout)
+	}
+	return l.Sender, nil
+}
+
+// NewHTLCOutput creates an output locked by an HTLC
+func NewHTLCOutput(value float32, lock *HTLCLock) *TXOutput {
+	return &TXOutput{Value: value, Address: lock.Address(), HTLC: lock}
+}
+
+// hashPreimage hashes a preimage with the named HTLC hash function
+func hashPreimage(hashType string, preimage []byte) []byte {
+	switch hashType {
+	case HTLCHashSHA256:
+		hash := sha256.Sum256(preimage)
+		return hash[:]
+	case HTLCHashKeccak256:
+		return crypto.Keccak256(preimage)
+	}
+	return nil
+}
+
+// NewHTLCSpendTransaction spends the HTLC output txID:vout to the given
+// address. With a preimage the recipient redeems it; without one the sender
+// refunds it, and the transaction cannot be mined before the timeout height.
+func NewHTLCSpendTransaction(privateKeyHex string, txID []byte, vout int, preimage []byte, to string, fee float32, bc *Blockchain) (*Transaction, error) {
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	prevTx, err := bc.FindTransaction(txID)
+	if err != nil {
+		return nil, err
+	}
+	if vout < 0 || vout >= len(prevTx.Vout) || prevTx.Vout[vout].HTLC == nil {
+		return nil, errors.New("output is not an HTLC")
+	}
+	out := prevTx.Vout[vout]
+	lock := out.HTLC
+
+	spendable := false
+	for _, utxo := range bc.FindUTXOs(out.Address) {
+		if bytes.Equal(utxo.TxID, txID) && utxo.Vout == vout {
+			spendable = true
+		}
+	}
+	if !spendable {
+		return nil, errors.New("HTLC output is already spent")
+	}
+
+	var lockTime int64
+	if len(preimage) == 0 {
+		lockTime = int64(lock.Timeout)
+	}
+	signer, err := lock.spender(preimage, lockTime)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(signer, wallet.GetAddress()) {
+		return nil, fmt.Errorf("HTLC can only be spent by %s on this path", signer)
+	}
+
+	if to == "" {
+		to = signer
+	}
+	if !common.IsHexAddress(to) {
+		return nil, errors.New("invalid recipient address")
+	}
+	if fee < 0 || fee >= out.Value {
+		return nil, errors.New("fee must be smaller than the HTLC value")
+	}
+
+	tx := Transaction{
+		Vin:      []TXInput{{Txid: txID, Vout: vout, PubKey: crypto.FromECDSAPub(&wallet.PrivateKey.PublicKey), Preimage: preimage}},
+		Vout:     []TXOutput{*NewTXOutput(out.Value-fee, to)},
+		From:     out.Address,
+		To:       to,
+		Amount:   out.Value - fee,
+		Fee:      fee,
+		LockTime: lockTime,
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(&tx, wallet.PrivateKey)
+
+	return &tx, nil
+}
+
+// FindHTLCPreimage searches the mempool and the chain for an input that
+// revealed a preimage of hash
+func (bc *Blockchain) FindHTLCPreimage(hash []byte) ([]byte, error) {
+	match := func(tx *Transaction) []byte {
+		for _, vin := range tx.Vin {
+			if len(vin.Preimage) == 0 {
+				continue
+			}
+			if bytes.Equal(hashPreimage(HTLCHashSHA256, vin.Preimage), hash) ||
+				bytes.Equal(hashPreimage(HTLCHashKeccak256, vin.Preimage), hash) {
+				return vin.Preimage
+			}
+		}
+		return nil
+	}
+
+	for _, tx := range bc.GetPendingTransactions() {
+		if preimage := match(tx); preimage != nil {
+			return preimage, nil
+		}
+	}
+
+	bci := bc.Iterator()
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			if preimage := match(tx); preimage != nil {
+				return preimage, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return nil, errors.New("Preimage is not revealed")
+}
@@ -0,0 +1,367 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Keystore parameters, the scrypt cost Ethereum clients use by default
+const (
+	keystoreVersion = 3
+	scryptN         = 1 << 18
+	scryptR         = 8
+	scryptP         = 1
+	scryptDKLen     = 32
+)
+
+// ErrWrongPassphrase is returned when a key file cannot be decrypted
+var ErrWrongPassphrase = errors.New("could not decrypt key with the given passphrase")
+
+// keyFile is the Ethereum v3 JSON form of an encrypted key
+type keyFile struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	ID      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// Keystore is a directory of passphrase encrypted keys, one file per address
+// in the Ethereum v3 format, so they can be moved to and from other wallets
+type Keystore struct {
+	Dir string
+}
+
+// DefaultKeystoreDir returns the keystore directory next to the chain database
+func DefaultKeystoreDir() string {
+	return filepath.Join(DataDir(), "keystore")
+}
+
+// NewKeystore opens the keystore in dir, creating the directory if needed
+func NewKeystore(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create keystore directory: %v", err)
+	}
+	return &Keystore{Dir: dir}, nil
+}
+
+// NewAccount generates a key and stores it encrypted with passphrase
+func (ks *Keystore) NewAccount(passphrase string) (*Wallet, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+
+	wallet, err := NewWalletFromPrivateKey(hex.EncodeToString(crypto.FromECDSA(privateKey)))
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.store(wallet, passphrase); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// Import stores an existing private key encrypted with passphrase
+func (ks *Keystore) Import(privateKeyHex, passphrase string) (*Wallet, error) {
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ks.find(wallet.Address); err == nil {
+		return nil, fmt.Errorf("address %s is already in the keystore", wallet.GetAddress())
+	}
+	if err := ks.store(wallet, passphrase); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// Unlock decrypts the key of address with passphrase
+func (ks *Keystore) Unlock(address, passphrase string) (*Wallet, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+
+	path, err := ks.find(common.HexToAddress(address))
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %v", err)
+	}
+
+	var key keyFile
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %v", path, err)
+	}
+	privateKey, err := decryptKey(&key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := NewWalletFromPrivateKey(hex.EncodeToString(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(wallet.Address.Hex()[2:], key.Address) {
+		return nil, fmt.Errorf("key file %s holds the key of %s", path, wallet.GetAddress())
+	}
+
+	return wallet, nil
+}
+
+// Addresses returns the addresses in the keystore, sorted
+func (ks *Keystore) Addresses() ([]string, error) {
+	entries, err := os.ReadDir(ks.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %v", err)
+	}
+
+	var addresses []string
+	for _, entry := range entries {
+		if address, ok := keyFileAddress(entry.Name()); ok {
+			addresses = append(addresses, address.Hex())
+		}
+	}
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
+
+// store writes the encrypted key file of wallet, named the way Ethereum
+// clients name theirs: UTC--<time>--<address>
+func (ks *Keystore) store(wallet *Wallet, passphrase string) error {
+	key, err := encryptKey(wallet, passphrase)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(key, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key file: %v", err)
+	}
+
+	name := fmt.Sprintf("UTC--%s--%s", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), key.Address)
+	return os.WriteFile(filepath.Join(ks.Dir, name), data, 0600)
+}
+
+// find returns the key file of address
+func (ks *Keystore) find(address common.Address) (string, error) {
+	entries, err := os.ReadDir(ks.Dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keystore: %v", err)
+	}
+
+	for _, entry := range entries {
+		if fileAddress, ok := keyFileAddress(entry.Name()); ok && fileAddress == address {
+			return filepath.Join(ks.Dir, entry.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("address %s is not in the keystore", address.Hex())
+}
+
+// keyFileAddress parses the address at the end of a key file name
+func keyFileAddress(name string) (common.Address, bool) {
+	i := strings.LastIndex(name, "--")
+	if i < 0 || !strings.HasPrefix(name, "UTC--") {
+		return common.Address{}, false
+	}
+	address := name[i+2:]
+	if len(address) != 40 || !common.IsHexAddress(address) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(address), true
+}
+
+// encryptKey encrypts the private key of wallet with scrypt and AES-128-CTR
+func encryptKey(wallet *Wallet, passphrase string) (*keyFile, error) {
+	salt := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	id := make([]byte, 16)
+	for _, buf := range [][]byte{salt, iv, id} {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to read random bytes: %v", err)
+		}
+	}
+
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	cipherText, err := aesCTR(derivedKey[:16], iv, crypto.FromECDSA(wallet.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	// Random UUID, version 4
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return &keyFile{
+		Address: hex.EncodeToString(wallet.Address.Bytes()),
+		Crypto: cryptoJSON{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: keystoreVersion,
+	}, nil
+}
+
+// decryptKey returns the private key of a v3 key file. Both the scrypt and
+// the pbkdf2 key derivations of the format are supported.
+func decryptKey(key *keyFile, passphrase string) ([]byte, error) {
+	if key.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", key.Version)
+	}
+	if key.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher %s", key.Crypto.Cipher)
+	}
+
+	mac, err := hex.DecodeString(key.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mac: %v", err)
+	}
+	iv, err := hex.DecodeString(key.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid iv")
+	}
+	cipherText, err := hex.DecodeString(key.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %v", err)
+	}
+
+	derivedKey, err := deriveKey(&key.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrWrongPassphrase
+	}
+
+	return aesCTR(derivedKey[:16], iv, cipherText)
+}
+
+// Bounds on the key derivation parameters of imported key files, so a
+// crafted file cannot make decryption take unbounded memory or time. They
+// leave room for four times the scrypt cost this keystore writes.
+const (
+	maxScryptN      = 1 << 20
+	maxScryptR      = 16
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30 // 128·N·r bytes
+	maxPBKDF2Rounds = 10000000
+	minDKLen        = 32 // The MAC and the cipher key take 16 bytes each
+	maxDKLen        = 64
+)
+
+// deriveKey runs the key derivation function of a key file
+func deriveKey(c *cryptoJSON, passphrase string) ([]byte, error) {
+	intParam := func(name string, min, max int) (int, error) {
+		value, ok := c.KDFParams[name].(float64) // JSON numbers decode as float64
+		if !ok || value != float64(int(value)) || int(value) < min || int(value) > max {
+			return 0, fmt.Errorf("kdf parameter %s must be an integer between %d and %d", name, min, max)
+		}
+		return int(value), nil
+	}
+	salt, err := hex.DecodeString(fmt.Sprint(c.KDFParams["salt"]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	if len(salt) == 0 {
+		return nil, errors.New("empty salt")
+	}
+	dkLen, err := intParam("dklen", minDKLen, maxDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.KDF {
+	case "scrypt":
+		n, err := intParam("n", 2, maxScryptN)
+		if err != nil {
+			return nil, err
+		}
+		if n&(n-1) != 0 {
+			return nil, fmt.Errorf("kdf parameter n must be a power of two, got %d", n)
+		}
+		r, err := intParam("r", 1, maxScryptR)
+		if err != nil {
+			return nil, err
+		}
+		p, err := intParam("p", 1, maxScryptP)
+		if err != nil {
+			return nil, err
+		}
+		if 128*n*r > maxScryptMemory {
+			return nil, fmt.Errorf("scrypt parameters n=%d r=%d need more than %d bytes", n, r, maxScryptMemory)
+		}
+		return scrypt.Key([]byte(passphrase), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		if prf := fmt.Sprint(c.KDFParams["prf"]); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %s", prf)
+		}
+		rounds, err := intParam("c", 1, maxPBKDF2Rounds)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(sha256.New, passphrase, salt, rounds, dkLen)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %s", c.KDF)
+	}
+}
+
+// aesCTR encrypts or decrypts data with AES in counter mode
+func aesCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	return out, nil
+}
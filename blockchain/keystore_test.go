@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeriveKeyRejectsUnboundedParameters(t *testing.T) {
+	scryptParams := func(n, r, p, dkLen float64) *cryptoJSON {
+		return &cryptoJSON{KDF: "scrypt", KDFParams: map[string]interface{}{
+			"n": n, "r": r, "p": p, "dklen": dkLen, "salt": "00112233",
+		}}
+	}
+	pbkdf2Params := func(c float64) *cryptoJSON {
+		return &cryptoJSON{KDF: "pbkdf2", KDFParams: map[string]interface{}{
+			"c": c, "prf": "hmac-sha256", "dklen": float64(32), "salt": "00112233",
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		params *cryptoJSON
+		reason string
+	}{
+		{"zero n", scryptParams(0, 8, 1, 32), "kdf parameter n"},
+		{"huge n", scryptParams(1<<40, 8, 1, 32), "kdf parameter n"},
+		{"n not a power of two", scryptParams(1000, 8, 1, 32), "power of two"},
+		{"zero r", scryptParams(1024, 0, 1, 32), "kdf parameter r"},
+		{"zero p", scryptParams(1024, 8, 0, 32), "kdf parameter p"},
+		{"huge p", scryptParams(1024, 8, 1<<30, 32), "kdf parameter p"},
+		{"too much memory", scryptParams(1<<20, 16, 1, 32), "bytes"},
+		{"short dklen", scryptParams(1024, 8, 1, 16), "kdf parameter dklen"},
+		{"fractional r", scryptParams(1024, 8.5, 1, 32), "kdf parameter r"},
+		{"zero rounds", pbkdf2Params(0), "kdf parameter c"},
+		{"huge rounds", pbkdf2Params(1 << 40), "kdf parameter c"},
+	}
+	for _, test := range tests {
+		_, err := deriveKey(test.params, "passphrase")
+		if err == nil || !strings.Contains(err.Error(), test.reason) {
+			t.Errorf("%s: got %v, want an error about %q", test.name, err, test.reason)
+		}
+	}
+
+	empty := scryptParams(1024, 8, 1, 32)
+	empty.KDFParams["salt"] = ""
+	if _, err := deriveKey(empty, "passphrase"); err == nil {
+		t.Error("empty salt accepted")
+	}
+
+	if _, err := deriveKey(scryptParams(1024, 8, 1, 32), "passphrase"); err != nil {
+		t.Errorf("valid scrypt parameters rejected: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+)
+
+// MerkleRoot computes the merkle root of a list of transaction IDs. Each level
+// hashes adjacent pairs; an odd node out is paired with itself.
+func MerkleRoot(txIDs [][]byte) []byte {
+	if len(txIDs) == 0 {
+		return make([]byte, 32)
+	}
+
+	level := make([][]byte, len(txIDs))
+	for i, id := range txIDs {
+		hash := sha256.Sum256(id)
+		level[i] = hash[:]
+	}
+
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			hash := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, hash[:])
+		}
+		level = next
+	}
+
+	return level[0]
+}
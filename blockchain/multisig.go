@@ -0,0 +1,214 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MaxMultisigKeys is the largest number of addresses a multisig output can list
+const MaxMultisigKeys = 15
+
+// MultisigLock locks an output to Threshold signatures from distinct Addresses
+type MultisigLock struct {
+	Threshold int
+	Addresses []string
+}
+
+// NewMultisigLock validates and normalizes an m-of-n lock. Addresses are
+// checksummed and sorted so the same set always yields the same address.
+func NewMultisigLock(threshold int, addresses []string) (*MultisigLock, error) {
+	if len(addresses) == 0 || len(addresses) > MaxMultisigKeys {
+		return nil, fmt.Errorf("multisig needs between 1 and %d addresses", MaxMultisigKeys)
+	}
+	if threshold < 1 || threshold > len(addresses) {
+		return nil, fmt.Errorf("multisig threshold must be between 1 and %d", len(addresses))
+	}
+
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid multisig address %q", address)
+		}
+		address = common.HexToAddress(address).Hex()
+		if seen[address] {
+			return nil, fmt.Errorf("duplicate multisig address %s", address)
+		}
+		seen[address] = true
+		normalized = append(normalized, address)
+	}
+	sort.Strings(normalized)
+
+	return &MultisigLock{Threshold: threshold, Addresses: normalized}, nil
+}
+
+// Validate checks that a lock read from a transaction is well formed
+func (l *MultisigLock) Validate() error {
+	normalized, err := NewMultisigLock(l.Threshold, l.Addresses)
+	if err != nil {
+		return err
+	}
+	for i := range normalized.Addresses {
+		if normalized.Addresses[i] != l.Addresses[i] {
+			return errors.New("multisig addresses are not normalized")
+		}
+	}
+	return nil
+}
+
+// Address returns the address that multisig outputs with this lock pay to.
+// It is derived from the threshold and the sorted addresses, so anyone
+// holding the lock description can recompute it.
+func (l *MultisigLock) Address() string {
+	data := [][]byte{IntToHex(int64(l.Threshold))}
+	for _, address := range l.Addresses {
+		data = append(data, common.HexToAddress(address).Bytes())
+	}
+	hash := crypto.Keccak256(data...)
+	return common.BytesToAddress(hash[12:]).Hex()
+}
+
+// Contains reports whether address is one of the lock's signers
+func (l *MultisigLock) Contains(address string) bool {
+	for _, a := range l.Addresses {
+		if strings.EqualFold(a, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMultisigOutput creates an output spendable by Threshold of the lock's addresses
+func NewMultisigOutput(value float32, lock *MultisigLock) *TXOutput {
+	return &TXOutput{Value: value, Address: lock.Address(), Multisig: lock}
+}
+
+// verifyMultisig checks that sigs hold at least Threshold signatures over
+// dataHash from distinct members of the lock
+func verifyMultisig(dataHash []byte, sigs [][]byte, lock *MultisigLock) bool {
+	if lock.Validate() != nil {
+		return false
+	}
+
+	signers := make(map[common.Address]bool)
+	for _, sig := range sigs {
+		pubKey, err := crypto.SigToPub(dataHash, sig)
+		if err != nil {
+			return false
+		}
+		signer := crypto.PubkeyToAddress(*pubKey)
+		if signers[signer] || !lock.Contains(signer.Hex()) {
+			return false
+		}
+		signers[signer] = true
+	}
+
+	return len(signers) >= lock.Threshold
+}
+
+// NewMultisigSpendTransaction builds an unsigned transaction spending outputs
+// locked to lock. Co-signers sign the digests given by SignatureHashes and
+// add their signatures with AddCosignatures until the threshold is reached.
+func NewMultisigSpendTransaction(lock *MultisigLock, to string, amount, fee float32, opts TxOptions, bc *Blockchain) (*Transaction, error) {
+	if !common.IsHexAddress(to) {
+		return nil, errors.New("invalid recipient address")
+	}
+
+	from := lock.Address()
+	totalNeeded := amount + fee
+
+	// Only outputs locked by the multisig script can be spent with the
+	// signatures of its members, plain payments to the address cannot
+	acc := float32(0)
+	var inputs []TXInput
+	for _, utxo := range bc.FindPendingUTXOs(from) {
+		if acc >= totalNeeded {
+			break
+		}
+		if utxo.Output.Multisig == nil || utxo.Output.IsToken() {
+			continue
+		}
+		acc += utxo.Output.Value
+		inputs = append(inputs, TXInput{Txid: utxo.TxID, Vout: utxo.Vout, Sequence: opts.Sequence})
+	}
+	if acc < totalNeeded {
+		return nil, errors.New("not enough multisig funds to cover amount and fee")
+	}
+
+	outputs := []TXOutput{*NewTXOutput(amount, to)}
+	if acc > totalNeeded {
+		outputs = append(outputs, *NewMultisigOutput(acc-totalNeeded, lock)) // Change stays in the multisig
+	}
+
+	tx := Transaction{
+		Vin:      inputs,
+		Vout:     outputs,
+		From:     from,
+		To:       to,
+		Amount:   amount,
+		Fee:      fee,
+		LockTime: opts.LockTime,
+	}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// AddCosignatures adds one co-signature to each input of a multisig spend, in
+// input order. Each signature must come from a member of the spent output's
+// lock who has not signed that input yet. Nothing is added unless every
+// signature is valid.
+func (bc *Blockchain) AddCosignatures(tx *Transaction, signatures [][]byte) error {
+	if len(signatures) != len(tx.Vin) {
+		return fmt.Errorf("transaction has %d inputs but %d signatures were given", len(tx.Vin), len(signatures))
+	}
+
+	prevTXs, err := bc.findPrevTransactions(tx, bc.GetPendingTransactions())
+	if err != nil {
+		return err
+	}
+
+	txCopy := tx.TrimmedCopy()
+	for inID, vin := range tx.Vin {
+		prevOut := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		if prevOut.Multisig == nil {
+			return fmt.Errorf("input %d does not spend a multisig output", inID)
+		}
+
+		dataHash := txCopy.inputSignatureHash(inID, prevOut)
+		pubKey, err := crypto.SigToPub(dataHash, signatures[inID])
+		if err != nil {
+			return fmt.Errorf("signature %d is invalid: %v", inID, err)
+		}
+		signer := crypto.PubkeyToAddress(*pubKey)
+		if !prevOut.Multisig.Contains(signer.Hex()) {
+			return fmt.Errorf("signature %d is from %s, who is not a signer of the multisig", inID, signer.Hex())
+		}
+		if hasSigner(dataHash, vin.Signatures, signer) {
+			return fmt.Errorf("%s has already signed input %d", signer.Hex(), inID)
+		}
+	}
+
+	for inID, sig := range signatures {
+		tx.Vin[inID].Signatures = append(tx.Vin[inID].Signatures, sig)
+	}
+	return nil
+}
+
+// DeserializeTransaction decodes a transaction produced by Transaction.Serialize
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	var tx Transaction
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&tx); err != nil {
+		return nil, fmt.Errorf("invalid transaction encoding: %v", err)
+	}
+	return &tx, nil
+}
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Keys of recipient and miner
+const (
+	recipientKey = "59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d"
+	minerKey     = "5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a"
+)
+
+// cosign signs every input of tx as a co-signer holding privateKeyHex
+func cosign(t *testing.T, tx *Transaction, privateKeyHex string) [][]byte {
+	t.Helper()
+
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signatures [][]byte
+	for _, hash := range tx.SignatureHashes(tx.From) {
+		sig, err := crypto.Sign(hash, wallet.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signatures = append(signatures, sig)
+	}
+	return signatures
+}
+
+func TestAddCosignatures(t *testing.T) {
+	bc := newTestChain(t)
+
+	lock, err := NewMultisigLock(2, []string{devAddress, recipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund := NewUTXOTransaction(devKey, devAddress, lock.Address(), 10, 0, TxOptions{Multisig: lock}, bc)
+	if err := mineWith(bc, []*Transaction{fund}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := NewMultisigSpendTransaction(lock, miner, 9, 0, TxOptions{}, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Co-signers signing offline sign what the node asks for
+	hashes, err := bc.InputSignatureHashes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range tx.SignatureHashes(tx.From) {
+		if !bytes.Equal(hash, hashes[i]) {
+			t.Fatalf("input %d: offline sighash %x, node sighash %x", i, hash, hashes[i])
+		}
+	}
+
+	if err := bc.AddCosignatures(tx, cosign(t, tx, minerKey)); err == nil || !strings.Contains(err.Error(), "not a signer") {
+		t.Fatalf("signature of a non-member added with %v", err)
+	}
+	if err := bc.AddCosignatures(tx, cosign(t, tx, devKey)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddCosignatures(tx, cosign(t, tx, devKey)); err == nil || !strings.Contains(err.Error(), "already signed") {
+		t.Fatalf("second signature of the same member added with %v", err)
+	}
+	if bc.VerifyTransaction(tx) {
+		t.Fatal("spend with one of two signatures verified")
+	}
+
+	if err := bc.AddCosignatures(tx, cosign(t, tx, recipientKey)); err != nil {
+		t.Fatal(err)
+	}
+	if !bc.VerifyTransaction(tx) {
+		t.Fatal("spend with both signatures failed to verify")
+	}
+}
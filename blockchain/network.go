@@ -0,0 +1,137 @@
+package blockchain
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Network holds the parameters that distinguish one DYP network from another
+type Network struct {
+	Name string
+
+	// Genesis defines the first block together with the chain ID, initial
+	// difficulty and block reward schedule of the network
+	Genesis *Genesis
+
+	// NoRetargeting keeps the difficulty at its initial value forever
+	NoRetargeting bool
+
+	// OnDemandMining allows blocks to be generated instantly through the
+	// node's admin listener instead of waiting for external miners
+	OnDemandMining bool
+}
+
+// genesisBurnAddress receives the genesis reward of the public networks. No
+// key is known for it, so those coins can never be spent.
+const genesisBurnAddress = "0x000000000000000000000000000000000000dEaD"
+
+// Built-in networks. Mainnet and testnet burn their genesis reward, so every
+// node derives the same genesis block and nobody holds a premine; regtest
+// pre-funds the well-known development key
+// 0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80.
+var (
+	MainNet = &Network{
+		Name: "mainnet",
+		Genesis: &Genesis{
+			ChainID:         7410,
+			Timestamp:       1748736000, // 2025-06-01 00:00:00 UTC
+			Difficulty:      InitialDifficulty,
+			BlockReward:     50,
+			HalvingInterval: 2100000,
+			ExtraData:       genesisCoinbaseData,
+			Alloc: []GenesisAlloc{
+				{Address: genesisBurnAddress, Amount: 50},
+			},
+		},
+	}
+
+	TestNet = &Network{
+		Name: "testnet",
+		Genesis: &Genesis{
+			ChainID:         7411,
+			Timestamp:       1748736001,
+			Difficulty:      16,
+			BlockReward:     50,
+			HalvingInterval: 2100000,
+			ExtraData:       genesisCoinbaseData + " (testnet)",
+			Alloc: []GenesisAlloc{
+				{Address: genesisBurnAddress, Amount: 50},
+			},
+		},
+	}
+
+	RegTest = &Network{
+		Name: "regtest",
+		Genesis: &Genesis{
+			ChainID:         7412,
+			Timestamp:       1748736002,
+			Difficulty:      MinDifficulty,
+			BlockReward:     50,
+			HalvingInterval: 150,
+			ExtraData:       genesisCoinbaseData + " (regtest)",
+			Alloc: []GenesisAlloc{
+				{Address: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", Amount: 1000000},
+			},
+		},
+		NoRetargeting:  true,
+		OnDemandMining: true,
+	}
+)
+
+// activeNetwork is the network this node is running on
+var activeNetwork = MainNet
+
+// ActiveNetwork returns the network the node is configured for
+func ActiveNetwork() *Network {
+	return activeNetwork
+}
+
+// SetNetwork selects the network the node runs on and points the node at the
+// network's own database file
+func SetNetwork(network *Network) {
+	activeNetwork = network
+
+	dir := filepath.Dir(dbFile)
+	if network.Name == MainNet.Name {
+		dbFile = filepath.Join(dir, "blockchain.db")
+	} else {
+		dbFile = filepath.Join(dir, fmt.Sprintf("blockchain-%s.db", network.Name))
+	}
+}
+
+// NetworkByName looks up a built-in network by name, defaulting to mainnet
+func NetworkByName(name string) (*Network, error) {
+	switch strings.ToLower(name) {
+	case "", MainNet.Name:
+		return MainNet, nil
+	case TestNet.Name:
+		return TestNet, nil
+	case RegTest.Name:
+		return RegTest, nil
+	default:
+		return nil, fmt.Errorf("unknown network %q", name)
+	}
+}
+
+// LoadNetwork returns the named network, replacing its genesis with the one in
+// genesisPath when a path is given
+func LoadNetwork(name, genesisPath string) (*Network, error) {
+	network, err := NetworkByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if genesisPath != "" {
+		genesis, err := LoadGenesis(genesisPath)
+		if err != nil {
+			return nil, err
+		}
+
+		custom := *network
+		custom.Genesis = genesis
+		network = &custom
+	}
+
+	return network, nil
+}
@@ -0,0 +1,27 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The genesis hashes are consensus: changing one is a hard fork
+func TestBuiltInGenesisHashes(t *testing.T) {
+	tests := []struct {
+		network *Network
+		hash    string
+	}{
+		{MainNet, "0000002d5ae0b65d5d53ebe5944188f164d103ee70652c31902dd378fe7a87f9"},
+		{TestNet, "0000fb7af23793d9d552c57d936a7e290f44d26b70fbb9032d5fd6857a20404e"},
+		{RegTest, "5437681161c5ecc9f8e77efbf58e95773fe4f04ff2f725cf3cabc930d4b565c9"},
+	}
+	for _, test := range tests {
+		if err := test.network.Genesis.Validate(); err != nil {
+			t.Fatalf("%s: %v", test.network.Name, err)
+		}
+		genesis := test.network.Genesis.ToBlock(NewEngine(test.network))
+		if got := hex.EncodeToString(genesis.Hash); got != test.hash {
+			t.Errorf("%s genesis is %s, want %s", test.network.Name, got, test.hash)
+		}
+	}
+}
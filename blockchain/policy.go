@@ -0,0 +1,121 @@
+package blockchain
+
+import "fmt"
+
+// Default mempool policy. These are node rules for relaying and mining
+// transactions, not consensus rules: blocks with non-standard transactions
+// are still valid.
+const (
+	// DefaultMinRelayFeePerByte is the lowest fee rate, in DYP per serialized
+	// byte, accepted into the mempool
+	DefaultMinRelayFeePerByte = 0.00001
+
+	// DefaultDustThreshold is the smallest DYP output accepted into the mempool
+	DefaultDustThreshold = 0.001
+
+	// DefaultMaxTxSize is the largest serialized transaction accepted into the
+	// mempool
+	DefaultMaxTxSize = 100 * 1024
+
+	// float32Epsilon is the relative rounding of float32 amounts, used when
+	// comparing sums of inputs and outputs
+	float32Epsilon = 1.0 / (1 << 23)
+)
+
+// MempoolPolicy holds the rules a transaction must meet to enter the mempool
+type MempoolPolicy struct {
+	MinRelayFeePerByte float32
+	DustThreshold      float32
+	MaxTxSize          int
+}
+
+// DefaultMempoolPolicy is the policy nodes start with
+var DefaultMempoolPolicy = MempoolPolicy{
+	MinRelayFeePerByte: DefaultMinRelayFeePerByte,
+	DustThreshold:      DefaultDustThreshold,
+	MaxTxSize:          DefaultMaxTxSize,
+}
+
+// PolicyError explains why a transaction was refused by the mempool policy
+type PolicyError struct {
+	Reason string // Short machine-readable reason, e.g. "min-relay-fee"
+	Detail string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Reason + ": " + e.Detail
+}
+
+// policyError creates a PolicyError with a formatted detail message
+func policyError(reason, format string, args ...interface{}) *PolicyError {
+	return &PolicyError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// MinFee returns the lowest fee the policy accepts for a transaction of size bytes
+func (p MempoolPolicy) MinFee(size int) float32 {
+	return p.MinRelayFeePerByte * float32(size)
+}
+
+// SetMempoolPolicy replaces the node's mempool policy
+func (bc *Blockchain) SetMempoolPolicy(policy MempoolPolicy) {
+	bc.policy = policy
+}
+
+// MempoolPolicy returns the node's mempool policy
+func (bc *Blockchain) MempoolPolicy() MempoolPolicy {
+	return bc.policy
+}
+
+// CheckPolicy checks that tx is standard and pays at least the minimum relay
+// fee. Inputs may spend outputs of the pending transactions. It returns a
+// *PolicyError describing the first rule tx breaks.
+func (bc *Blockchain) CheckPolicy(tx *Transaction, pending []*Transaction) error {
+	policy := bc.policy
+
+	if tx.IsCoinbase() {
+		return policyError("coinbase", "coinbase transactions are only accepted in blocks")
+	}
+	if len(tx.Vin) == 0 {
+		return policyError("no-inputs", "transaction has no inputs")
+	}
+	if len(tx.Vout) == 0 {
+		return policyError("no-outputs", "transaction has no outputs")
+	}
+
+	size := tx.Size()
+	if size > policy.MaxTxSize {
+		return policyError("tx-size", "transaction is %d bytes, the maximum is %d", size, policy.MaxTxSize)
+	}
+
+	if err := tx.CheckOutputs(); err != nil {
+		return policyError("nonstandard-output", "%v", err)
+	}
+
+	for i, out := range tx.Vout {
+		if out.IsData() || out.IsToken() {
+			continue
+		}
+		if out.Value < policy.DustThreshold {
+			return policyError("dust", "output %d pays %f DYP, below the dust threshold of %f DYP", i, out.Value, policy.DustThreshold)
+		}
+	}
+
+	if tx.Fee < 0 {
+		return policyError("negative-fee", "fee cannot be negative")
+	}
+
+	prevTXs, err := bc.findPrevTransactions(tx, pending)
+	if err != nil {
+		return policyError("missing-inputs", "%v", err)
+	}
+	// The declared fee is what block producers collect, so it must be paid
+	if err := tx.checkFee(prevTXs); err != nil {
+		return policyError("fee-mismatch", "%v", err)
+	}
+
+	if minFee := policy.MinFee(size); tx.Fee < minFee {
+		return policyError("min-relay-fee", "fee %f DYP is below the minimum relay fee of %f DYP for %d bytes", tx.Fee, minFee, size)
+	}
+
+	return nil
+}
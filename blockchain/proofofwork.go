@@ -1,104 +1,128 @@
 package blockchain
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"log"
 	"math"
 	"math/big"
+	"time"
 )
 
-const maxNonce = math.MaxInt64
+const maxNonce = uint64(math.MaxUint64)
 
-// currentDifficulty stores the current mining difficulty
-var currentDifficulty = InitialDifficulty
-
-// GetTargetBits returns the current mining difficulty
-func GetTargetBits() int {
-	return currentDifficulty
+// PowEngine is the SHA-256 proof-of-work consensus engine. A block is valid
+// when the hash of its header is below the target given by its difficulty bits.
+type PowEngine struct {
+	genesis       *Genesis
+	noRetargeting bool
 }
 
-// SetDifficulty sets the current mining difficulty
-func SetDifficulty(difficulty int) {
-	if difficulty < MinDifficulty {
-		difficulty = MinDifficulty
-	}
-	if difficulty > MaxDifficulty {
-		difficulty = MaxDifficulty
+// NewPowEngine creates a proof-of-work engine for a network
+func NewPowEngine(network *Network) *PowEngine {
+	return &PowEngine{
+		genesis:       network.Genesis,
+		noRetargeting: network.NoRetargeting,
 	}
-	currentDifficulty = difficulty
 }
 
-// ProofOfWork represents a proof-of-work
-type ProofOfWork struct {
-	block  *Block
-	target *big.Int
+// powTarget returns the largest hash allowed at the given difficulty
+func powTarget(bits int) *big.Int {
+	target := big.NewInt(1)
+	target.Lsh(target, uint(256-bits))
+	return target
 }
 
-// NewProofOfWork builds and returns a ProofOfWork
-func NewProofOfWork(b *Block) *ProofOfWork {
-	target := big.NewInt(1)
-	target.Lsh(target, uint(256-currentDifficulty))
+// Seal searches for a nonce whose header hash meets the block's target
+func (e *PowEngine) Seal(chain ChainReader, block *Block, stop <-chan struct{}) error {
+	var hashInt big.Int
+	target := powTarget(block.Bits)
+	header := block.BlockHeader
 
-	pow := &ProofOfWork{b, target}
-	return pow
-}
+	log.Printf("[Miner] Starting proof of work with target bits: %d", block.Bits)
 
-// prepareData prepares data for hashing
-func (pow *ProofOfWork) prepareData(nonce int) []byte {
-	data := bytes.Join(
-		[][]byte{
-			pow.block.PrevBlockHash,
-			pow.block.HashTransactions(),
-			IntToHex(pow.block.Timestamp),
-			IntToHex(int64(currentDifficulty)),
-			IntToHex(int64(nonce)),
-		},
-		[]byte{},
-	)
-
-	return data
-}
+	for nonce := uint64(0); ; nonce++ {
+		if nonce%100000 == 0 {
+			select {
+			case <-stop:
+				return ErrSealStopped
+			default:
+			}
+		}
 
-// Run performs a proof-of-work
-func (pow *ProofOfWork) Run() (int, []byte) {
-	var hashInt big.Int
-	var hash [32]byte
-	nonce := 0
-
-	log.Printf("[Miner] Starting proof of work with target bits: %d", currentDifficulty)
-	fmt.Printf("[Miner] Mining a new block")
-	for nonce < maxNonce {
-		data := pow.prepareData(nonce)
-		hash = sha256.Sum256(data)
+		header.Nonce = nonce
+		hash := sha256.Sum256(header.Serialize())
 		hashInt.SetBytes(hash[:])
 
-		if hashInt.Cmp(pow.target) == -1 {
+		if hashInt.Cmp(target) == -1 {
 			log.Printf("[Miner] Found valid proof of work - Hash: %x, Nonce: %d", hash, nonce)
-			break
-		} else {
-			nonce++
+			block.Nonce = nonce
+			block.Hash = hash[:]
+			return nil
 		}
 
-		if nonce%100000 == 0 {
-			fmt.Printf("\r[Miner] Mining... Current nonce: %d", nonce)
+		if nonce == maxNonce {
+			return ErrNonceSpaceExhausted
 		}
 	}
-	fmt.Print("\n\n")
-
-	return nonce, hash[:]
 }
 
-// Validate validates block's PoW
-func (pow *ProofOfWork) Validate() bool {
+// VerifyHeader checks the header's timestamp, difficulty and proof of work.
+// The timestamp must be after the median time past of the parent and at most
+// MaxTimeDeviation ahead of the local clock.
+func (e *PowEngine) VerifyHeader(chain ChainReader, header *BlockHeader) error {
+	if limit := time.Now().Add(MaxTimeDeviation).Unix(); header.Timestamp > limit {
+		return fmt.Errorf("block timestamp %d is too far in the future, limit %d", header.Timestamp, limit)
+	}
+
+	if header.Height == 0 {
+		if header.Bits != e.genesis.Difficulty {
+			return fmt.Errorf("genesis difficulty %d, want %d", header.Bits, e.genesis.Difficulty)
+		}
+	} else {
+		parent, err := chain.GetBlockHeader(header.PrevBlockHash)
+		if err != nil {
+			return fmt.Errorf("unknown parent block %x", header.PrevBlockHash)
+		}
+		if mtp := MedianTimePast(chain, parent); header.Timestamp <= mtp {
+			return fmt.Errorf("block timestamp %d is not after the median time past %d", header.Timestamp, mtp)
+		}
+		if want := e.CalcDifficulty(chain, parent); header.Bits != want {
+			return fmt.Errorf("wrong difficulty bits %d, want %d", header.Bits, want)
+		}
+	}
+
 	var hashInt big.Int
+	hashInt.SetBytes(header.Hash())
+	if hashInt.Cmp(powTarget(header.Bits)) != -1 {
+		return fmt.Errorf("invalid proof of work")
+	}
 
-	data := pow.prepareData(pow.block.Nonce)
-	hash := sha256.Sum256(data)
-	hashInt.SetBytes(hash[:])
+	return nil
+}
+
+// CalcDifficulty retargets every DifficultyAdjustmentInterval blocks from the
+// average block time of the previous interval, read from the chain itself
+func (e *PowEngine) CalcDifficulty(chain ChainReader, parent *BlockHeader) int {
+	height := parent.Height + 1
+	if e.noRetargeting || height%DifficultyAdjustmentInterval != 0 {
+		return parent.Bits
+	}
 
-	isValid := hashInt.Cmp(pow.target) == -1
+	first := parent
+	for i := 0; i < DifficultyAdjustmentInterval-1; i++ {
+		prev, err := chain.GetBlockHeader(first.PrevBlockHash)
+		if err != nil {
+			return parent.Bits
+		}
+		first = prev
+	}
+
+	timespan := time.Duration(parent.Timestamp-first.Timestamp) * time.Second
+	return CalculateNextDifficulty(parent.Bits, timespan/(DifficultyAdjustmentInterval-1))
+}
 
-	return isValid
+// BlockReward returns the network's halving block subsidy
+func (e *PowEngine) BlockReward(height int) float32 {
+	return e.genesis.Subsidy(height)
 }
@@ -0,0 +1,100 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signatureLength is the length of a recoverable secp256k1 signature
+const signatureLength = crypto.SignatureLength
+
+// NewUnsignedUTXOTransactionWithFeeRate builds an unsigned payment paying
+// feePerByte DYP for each byte the transaction will have once signed
+func NewUnsignedUTXOTransactionWithFeeRate(from, to string, amount, feePerByte float32, pubKey []byte, opts TxOptions, bc *Blockchain) (*Transaction, error) {
+	fee := float32(0)
+	for {
+		tx, err := NewUnsignedUTXOTransaction(from, to, amount, fee, pubKey, opts, bc)
+		if err != nil {
+			return nil, err
+		}
+		if want := feePerByte * float32(tx.signedSize()); fee < want {
+			fee = want
+			continue
+		}
+		return tx, nil
+	}
+}
+
+// signedSize returns the size tx will have once every input carries a signature
+func (tx *Transaction) signedSize() int {
+	signed := *tx
+	signed.Vin = make([]TXInput, len(tx.Vin))
+	for i, vin := range tx.Vin {
+		if len(vin.Signature) == 0 {
+			vin.Signature = make([]byte, signatureLength)
+		}
+		signed.Vin[i] = vin
+	}
+	return signed.Size()
+}
+
+// UnsignedID returns the ID of tx: the hash of the transaction without its
+// input signatures, which is how the transaction builders set it
+func (tx *Transaction) UnsignedID() []byte {
+	unsigned := *tx
+	unsigned.Vin = make([]TXInput, len(tx.Vin))
+	for i, vin := range tx.Vin {
+		vin.Signature = nil
+		vin.Signatures = nil
+		unsigned.Vin[i] = vin
+	}
+	return unsigned.Hash()
+}
+
+// InputSignatureHashes returns the digest each input of tx must sign. Signing
+// a digest with the key of the spent output, as a 65 byte [R || S || V]
+// secp256k1 signature, gives the input's signature.
+func (bc *Blockchain) InputSignatureHashes(tx *Transaction) ([][]byte, error) {
+	prevTXs, err := bc.findPrevTransactions(tx, bc.GetPendingTransactions())
+	if err != nil {
+		return nil, err
+	}
+
+	txCopy := tx.TrimmedCopy()
+	hashes := make([][]byte, len(tx.Vin))
+	for inID, vin := range tx.Vin {
+		prevOut := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		hashes[inID] = txCopy.inputSignatureHash(inID, prevOut)
+	}
+
+	return hashes, nil
+}
+
+// SignatureHashes returns the digest each input of tx must sign when every
+// input spends an output of from: the sender of a payment, or the multisig
+// address of a multisig spend. It needs no chain, so signers can compute the
+// digests from the transaction itself instead of trusting a node.
+func (tx *Transaction) SignatureHashes(from string) [][]byte {
+	txCopy := tx.TrimmedCopy()
+	hashes := make([][]byte, len(tx.Vin))
+	for inID := range tx.Vin {
+		hashes[inID] = txCopy.inputSignatureHash(inID, TXOutput{Address: from})
+	}
+	return hashes
+}
+
+// AttachSignatures sets the signature of each input of tx, in input order
+func (tx *Transaction) AttachSignatures(signatures [][]byte) error {
+	if len(signatures) != len(tx.Vin) {
+		return fmt.Errorf("transaction has %d inputs but %d signatures were given", len(tx.Vin), len(signatures))
+	}
+	for i, sig := range signatures {
+		if len(sig) != signatureLength {
+			return fmt.Errorf("signature %d is %d bytes, expected %d", i, len(sig), signatureLength)
+		}
+		tx.Vin[i].Signature = sig
+	}
+	return nil
+}
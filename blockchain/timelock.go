@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const (
+	// LockTimeThreshold separates lock times that are block heights (below)
+	// from lock times that are unix timestamps (at or above)
+	LockTimeThreshold = 500000000
+
+	// SequenceLockTimeDisableFlag turns off the relative lock of an input
+	SequenceLockTimeDisableFlag = 1 << 31
+
+	// SequenceLockTimeTypeFlag makes a relative lock count time instead of blocks
+	SequenceLockTimeTypeFlag = 1 << 22
+
+	// SequenceLockTimeMask extracts the relative lock value from a sequence
+	SequenceLockTimeMask = 0x0000ffff
+
+	// SequenceLockTimeGranularity is the shift converting time-based relative
+	// lock values into seconds (units of 512 seconds)
+	SequenceLockTimeGranularity = 9
+)
+
+// IsFinal reports whether the absolute lock time allows the transaction into
+// a block at the given height and time
+func (tx *Transaction) IsFinal(height int, blockTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+	if tx.LockTime < LockTimeThreshold {
+		return tx.LockTime <= int64(height)
+	}
+	return tx.LockTime <= blockTime
+}
+
+// CheckTransactionLocks checks the absolute and relative lock times of tx for
+// inclusion in a block at the given height and time. Inputs may spend outputs
+// of the pending transactions, which are treated as part of that block.
+func (bc *Blockchain) CheckTransactionLocks(tx *Transaction, pending []*Transaction, height int, blockTime int64) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	if !tx.IsFinal(height, blockTime) {
+		if tx.LockTime < LockTimeThreshold {
+			return fmt.Errorf("transaction is locked until block %d", tx.LockTime)
+		}
+		return fmt.Errorf("transaction is locked until unix time %d", tx.LockTime)
+	}
+
+	for _, vin := range tx.Vin {
+		if vin.Sequence&SequenceLockTimeDisableFlag != 0 {
+			continue
+		}
+		value := int64(vin.Sequence & SequenceLockTimeMask)
+		if value == 0 {
+			continue
+		}
+
+		originHeight, originTime, err := bc.outputOrigin(vin.Txid, pending, height, blockTime)
+		if err != nil {
+			return err
+		}
+
+		if vin.Sequence&SequenceLockTimeTypeFlag != 0 {
+			unlockTime := originTime + value<<SequenceLockTimeGranularity
+			if blockTime < unlockTime {
+				return fmt.Errorf("input %x:%d is locked until unix time %d", vin.Txid, vin.Vout, unlockTime)
+			}
+		} else {
+			unlockHeight := originHeight + int(value)
+			if height < unlockHeight {
+				return fmt.Errorf("input %x:%d is locked until block %d", vin.Txid, vin.Vout, unlockHeight)
+			}
+		}
+	}
+
+	return nil
+}
+
+// outputOrigin returns the height and time of the block that created the
+// outputs of transaction txID. Pending transactions belong to the block being
+// checked at height and blockTime.
+func (bc *Blockchain) outputOrigin(txID []byte, pending []*Transaction, height int, blockTime int64) (int, int64, error) {
+	for _, tx := range pending {
+		if bytes.Equal(tx.ID, txID) {
+			return height, blockTime, nil
+		}
+	}
+
+	bci := bc.Iterator()
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, txID) {
+				return block.Height, block.Timestamp, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return 0, 0, errors.New("Transaction is not found")
+}
@@ -0,0 +1,307 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TokenIssuance defines a new token. The whole supply is created by the
+// issuance transaction and assigned to its token outputs.
+type TokenIssuance struct {
+	Name     string
+	Symbol   string
+	Supply   uint64
+	Metadata string
+}
+
+// TokenInfo describes an issued token and where it was created
+type TokenInfo struct {
+	ID string
+	TokenIssuance
+	Issuer string
+	TxID   []byte
+	Height int
+}
+
+// Validate checks the issuance fields
+func (ti *TokenIssuance) Validate() error {
+	if ti.Name == "" || len(ti.Name) > 32 {
+		return errors.New("token name must be between 1 and 32 bytes")
+	}
+	if ti.Symbol == "" || len(ti.Symbol) > 12 {
+		return errors.New("token symbol must be between 1 and 12 bytes")
+	}
+	if ti.Supply == 0 {
+		return errors.New("token supply must be positive")
+	}
+	if len(ti.Metadata) > MaxTokenMetadataSize {
+		return fmt.Errorf("token metadata exceeds %d bytes", MaxTokenMetadataSize)
+	}
+	return nil
+}
+
+// IssuedTokenID returns the ID of the token created by tx, or "" if it is not
+// an issuance. The ID commits to the first spent outpoint, which can only be
+// spent once, so token IDs never collide.
+func (tx *Transaction) IssuedTokenID() string {
+	if tx.Issuance == nil || len(tx.Vin) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(crypto.Keccak256(tx.Vin[0].Txid, IntToHex(int64(tx.Vin[0].Vout))))
+}
+
+// NewTokenOutput creates an output holding amount of a token
+func NewTokenOutput(tokenID string, amount uint64, address string) *TXOutput {
+	return &TXOutput{Value: 0, Address: address, TokenID: tokenID, TokenAmount: amount}
+}
+
+// IsToken reports whether the output holds tokens
+func (out *TXOutput) IsToken() bool {
+	return out.TokenID != ""
+}
+
+// checkTokenOutputs checks the structure of token outputs and issuances
+func (tx *Transaction) checkTokenOutputs() error {
+	if tx.Issuance != nil {
+		if tx.IsCoinbase() || len(tx.Vin) == 0 {
+			return errors.New("token issuance needs a spent input")
+		}
+		if err := tx.Issuance.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i, out := range tx.Vout {
+		if !out.IsToken() {
+			continue
+		}
+		if tx.IsCoinbase() {
+			return errors.New("coinbase cannot carry tokens")
+		}
+		if len(out.TokenID) != 64 {
+			return fmt.Errorf("output %d has an invalid token ID", i)
+		}
+		if out.TokenAmount == 0 || out.Value != 0 {
+			return fmt.Errorf("output %d must carry a token amount and no DYP", i)
+		}
+		if out.Multisig != nil || out.HTLC != nil || !common.IsHexAddress(out.Address) {
+			return fmt.Errorf("output %d must pay tokens to a plain address", i)
+		}
+	}
+
+	return nil
+}
+
+// CheckTokens checks that every token amount spent by tx is conserved in its
+// outputs. An issuance adds its supply for the token it creates.
+func (tx *Transaction) CheckTokens(prevTXs map[string]Transaction) error {
+	balance := make(map[string]uint64)
+
+	for _, vin := range tx.Vin {
+		if tx.IsCoinbase() {
+			break
+		}
+		prevOut := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		if prevOut.IsToken() {
+			balance[prevOut.TokenID] += prevOut.TokenAmount
+		}
+	}
+
+	if tokenID := tx.IssuedTokenID(); tokenID != "" {
+		balance[tokenID] += tx.Issuance.Supply
+	}
+
+	outputs := make(map[string]uint64)
+	for _, out := range tx.Vout {
+		if out.IsToken() {
+			outputs[out.TokenID] += out.TokenAmount
+		}
+	}
+
+	for tokenID, amount := range outputs {
+		if balance[tokenID] != amount {
+			return fmt.Errorf("token %s: outputs %d do not match inputs %d", tokenID, amount, balance[tokenID])
+		}
+	}
+	for tokenID, amount := range balance {
+		if outputs[tokenID] != amount {
+			return fmt.Errorf("token %s: inputs %d do not match outputs %d", tokenID, amount, outputs[tokenID])
+		}
+	}
+
+	return nil
+}
+
+// NewTokenIssuanceTransaction creates a token and assigns its whole supply to
+// from, which pays the fee
+func NewTokenIssuanceTransaction(privateKeyHex, from string, issuance TokenIssuance, fee float32, bc *Blockchain) (*Transaction, error) {
+	if err := issuance.Validate(); err != nil {
+		return nil, err
+	}
+	if fee <= 0 {
+		return nil, errors.New("a fee is required to issue a token")
+	}
+	if !common.IsHexAddress(from) {
+		return nil, errors.New("invalid address format")
+	}
+
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, validOutputs := bc.FindSpendableOutputs(from, fee)
+	if acc < fee {
+		return nil, errors.New("not enough funds to cover the fee")
+	}
+
+	inputs, err := spendInputs(validOutputs, crypto.FromECDSAPub(&wallet.PrivateKey.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := Transaction{
+		Vin:      inputs,
+		From:     from,
+		To:       from,
+		Fee:      fee,
+		Issuance: &issuance,
+	}
+	tx.Vout = append(tx.Vout, *NewTokenOutput(tx.IssuedTokenID(), issuance.Supply, from))
+	if acc > fee {
+		tx.Vout = append(tx.Vout, *NewTXOutput(acc-fee, from))
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(&tx, wallet.PrivateKey)
+
+	return &tx, nil
+}
+
+// NewTokenTransaction sends amount of a token from one address to another.
+// The fee is paid in DYP by from.
+func NewTokenTransaction(privateKeyHex, from, to, tokenID string, amount uint64, fee float32, bc *Blockchain) (*Transaction, error) {
+	if !common.IsHexAddress(from) || !common.IsHexAddress(to) {
+		return nil, errors.New("invalid address format")
+	}
+	if amount == 0 {
+		return nil, errors.New("token amount must be positive")
+	}
+	if fee < 0 {
+		return nil, errors.New("fee cannot be negative")
+	}
+
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	pubKeyBytes := crypto.FromECDSAPub(&wallet.PrivateKey.PublicKey)
+
+	var inputs []TXInput
+	tokens := uint64(0)
+	for _, utxo := range bc.FindPendingUTXOs(from) {
+		if tokens >= amount {
+			break
+		}
+		if utxo.Output.TokenID != tokenID {
+			continue
+		}
+		tokens += utxo.Output.TokenAmount
+		inputs = append(inputs, TXInput{Txid: utxo.TxID, Vout: utxo.Vout, PubKey: pubKeyBytes})
+	}
+	if tokens < amount {
+		return nil, errors.New("not enough tokens")
+	}
+
+	acc, validOutputs := bc.FindSpendableOutputs(from, fee)
+	if acc < fee {
+		return nil, errors.New("not enough funds to cover the fee")
+	}
+	feeInputs, err := spendInputs(validOutputs, pubKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, feeInputs...)
+
+	outputs := []TXOutput{*NewTokenOutput(tokenID, amount, to)}
+	if tokens > amount {
+		outputs = append(outputs, *NewTokenOutput(tokenID, tokens-amount, from))
+	}
+	if acc > fee {
+		outputs = append(outputs, *NewTXOutput(acc-fee, from))
+	}
+
+	tx := Transaction{
+		Vin:  inputs,
+		Vout: outputs,
+		From: from,
+		To:   to,
+		Fee:  fee,
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(&tx, wallet.PrivateKey)
+
+	return &tx, nil
+}
+
+// spendInputs turns outputs found by FindSpendableOutputs into inputs
+func spendInputs(validOutputs map[string][]int, pubKey []byte) ([]TXInput, error) {
+	var inputs []TXInput
+	for txid, outs := range validOutputs {
+		txID, err := hex.DecodeString(txid)
+		if err != nil {
+			return nil, err
+		}
+		for _, out := range outs {
+			inputs = append(inputs, TXInput{Txid: txID, Vout: out, PubKey: pubKey})
+		}
+	}
+	return inputs, nil
+}
+
+// FindToken returns the issuance of a token
+func (bc *Blockchain) FindToken(tokenID string) (*TokenInfo, error) {
+	bci := bc.Iterator()
+
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			if tx.Issuance != nil && tx.IssuedTokenID() == tokenID {
+				return &TokenInfo{
+					ID:            tokenID,
+					TokenIssuance: *tx.Issuance,
+					Issuer:        tx.From,
+					TxID:          tx.ID,
+					Height:        block.Height,
+				}, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return nil, errors.New("Token is not found")
+}
+
+// GetTokenBalances returns the token amounts address holds once the mempool
+// transactions confirm, keyed by token ID, which is what it can transfer
+func (bc *Blockchain) GetTokenBalances(address string) map[string]uint64 {
+	balances := make(map[string]uint64)
+	if !common.IsHexAddress(address) {
+		return balances
+	}
+
+	for _, utxo := range bc.FindPendingUTXOs(address) {
+		if utxo.Output.IsToken() {
+			balances[utxo.Output.TokenID] += utxo.Output.TokenAmount
+		}
+	}
+
+	return balances
+}
@@ -3,17 +3,19 @@ package blockchain
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-const MINING_REWARD = float32(50)
-
 // Transaction represents a blockchain transaction
 type Transaction struct {
 	ID        []byte
@@ -24,6 +26,25 @@ type Transaction struct {
 	Amount    float32
 	Fee       float32
 	Signature []byte
+
+	// ExtraNonce is only used by coinbase transactions. Miners roll it to
+	// change the merkle root once the header nonce space is exhausted.
+	ExtraNonce uint64
+
+	// LockTime is the earliest block height (below LockTimeThreshold) or unix
+	// time at which the transaction can be included in a block. 0 disables it.
+	LockTime int64
+
+	// Issuance is set on transactions that create a new token
+	Issuance *TokenIssuance
+}
+
+// TxOptions holds the optional fields of a new transaction
+type TxOptions struct {
+	LockTime int64         // Absolute lock, see Transaction.LockTime
+	Sequence uint32        // Relative lock applied to every input, see TXInput.Sequence
+	Multisig *MultisigLock // Locks the payment output to a multisig instead of to the recipient
+	HTLC     *HTLCLock     // Locks the payment output to a hash time lock instead of to the recipient
 }
 
 // TXInput represents a transaction input
@@ -32,6 +53,17 @@ type TXInput struct {
 	Vout      int
 	Signature []byte
 	PubKey    []byte
+
+	// Sequence encodes a relative lock: the spent output must be buried
+	// this many blocks (or 512-second units with SequenceLockTimeTypeFlag)
+	// before the input is valid
+	Sequence uint32
+
+	// Signatures holds the co-signatures of an input spending a multisig output
+	Signatures [][]byte
+
+	// Preimage redeems an HTLC output; it is empty on the refund path
+	Preimage []byte
 }
 
 // UsesKey checks whether the address initiated the transaction
@@ -49,18 +81,31 @@ func (in *TXInput) UsesKey(address string) bool {
 type TXOutput struct {
 	Value   float32
 	Address string // address
+
+	// Multisig is set on m-of-n outputs; Address is then the lock's address
+	Multisig *MultisigLock
+
+	// HTLC is set on hash time-locked outputs; Address is then the lock's address
+	HTLC *HTLCLock
+
+	// Data is set on provably unspendable data outputs, which carry no value
+	// and no address
+	Data []byte
+
+	// TokenID and TokenAmount tag an output with an amount of a native token
+	TokenID     string
+	TokenAmount uint64
 }
 
-// NewCoinbaseTx creates a new coinbase transaction
-func NewCoinbaseTx(to, data string, isGenesis bool, totalFees float32) *Transaction {
+// NewCoinbaseTx creates a new coinbase transaction for the block at height
+// paying reward, which is the engine's block reward plus transaction fees
+func NewCoinbaseTx(to, data string, height int, reward float32) *Transaction {
 	if !common.IsHexAddress(to) {
 		log.Panic("Invalid miner address")
 	}
 
-	// Mining reward is always 50 DYP plus transaction fees
-	reward := MINING_REWARD + totalFees
-
-	txin := TXInput{[]byte{}, -1, nil, nil}
+	// The block height in the coinbase input makes every coinbase txid unique
+	txin := TXInput{Txid: []byte{}, Vout: -1, Signature: coinbaseScript(height, []byte(data))}
 	txout := NewTXOutput(reward, to)
 	tx := Transaction{
 		ID:        []byte{},
@@ -77,19 +122,39 @@ func NewCoinbaseTx(to, data string, isGenesis bool, totalFees float32) *Transact
 }
 
 // NewUTXOTransaction creates a new transaction
-func NewUTXOTransaction(privateKeyHex, from, to string, amount, fee float32, bc *Blockchain) *Transaction {
+func NewUTXOTransaction(privateKeyHex, from, to string, amount, fee float32, opts TxOptions, bc *Blockchain) *Transaction {
+	// Create wallet from private key
+	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	tx, err := NewUnsignedUTXOTransaction(from, to, amount, fee, wallet.PublicKey, opts, bc)
+	if err != nil {
+		log.Panic(err)
+	}
+	bc.SignTransaction(tx, wallet.PrivateKey)
+
+	return tx
+}
+
+// NewUnsignedUTXOTransaction builds a payment from the outputs of from without
+// signing it. pubKey, the uncompressed public key of from, is recorded in the
+// inputs and may be nil when the signer does not share it.
+func NewUnsignedUTXOTransaction(from, to string, amount, fee float32, pubKey []byte, opts TxOptions, bc *Blockchain) (*Transaction, error) {
 	var inputs []TXInput
 	var outputs []TXOutput
 
-	// Validate addresses
-	if !common.IsHexAddress(from) || !common.IsHexAddress(to) {
-		log.Panic("Invalid address format")
+	if opts.Multisig != nil {
+		to = opts.Multisig.Address()
+	}
+	if opts.HTLC != nil {
+		to = opts.HTLC.Address()
 	}
 
-	// Create wallet from private key
-	wallet, err := NewWalletFromPrivateKey(privateKeyHex)
-	if err != nil {
-		log.Panic(err)
+	// Validate addresses
+	if !common.IsHexAddress(from) || !common.IsHexAddress(to) {
+		return nil, errors.New("invalid address format")
 	}
 
 	// Total amount needed is amount + fee
@@ -97,26 +162,29 @@ func NewUTXOTransaction(privateKeyHex, from, to string, amount, fee float32, bc
 	acc, validOutputs := bc.FindSpendableOutputs(from, totalNeeded)
 
 	if acc < totalNeeded {
-		log.Panic("ERROR: Not enough funds to cover amount and fee")
+		return nil, errors.New("not enough funds to cover amount and fee")
 	}
 
 	// Build a list of inputs
 	for txid, outs := range validOutputs {
 		txID, err := hex.DecodeString(txid)
 		if err != nil {
-			log.Panic(err)
+			return nil, err
 		}
 
 		for _, out := range outs {
-			// Get the public key bytes
-			pubKeyBytes := crypto.FromECDSAPub(&wallet.PrivateKey.PublicKey)
-			input := TXInput{txID, out, nil, pubKeyBytes}
-			inputs = append(inputs, input)
+			inputs = append(inputs, TXInput{Txid: txID, Vout: out, PubKey: pubKey, Sequence: opts.Sequence})
 		}
 	}
 
 	// Build a list of outputs
-	outputs = append(outputs, *NewTXOutput(amount, to)) // Payment to recipient
+	if opts.Multisig != nil {
+		outputs = append(outputs, *NewMultisigOutput(amount, opts.Multisig)) // Payment locked to the multisig
+	} else if opts.HTLC != nil {
+		outputs = append(outputs, *NewHTLCOutput(amount, opts.HTLC)) // Payment locked to the HTLC
+	} else {
+		outputs = append(outputs, *NewTXOutput(amount, to)) // Payment to recipient
+	}
 	if acc > totalNeeded {
 		outputs = append(outputs, *NewTXOutput(acc-totalNeeded, from)) // Change back to sender
 	}
@@ -130,68 +198,67 @@ func NewUTXOTransaction(privateKeyHex, from, to string, amount, fee float32, bc
 		Amount:    amount,
 		Fee:       fee,
 		Signature: nil,
+		LockTime:  opts.LockTime,
 	}
 	tx.ID = tx.Hash()
-	bc.SignTransaction(&tx, wallet.PrivateKey)
 
-	return &tx
+	return &tx, nil
 }
 
-// Hash returns the hash of the Transaction
-func (tx *Transaction) Hash() []byte {
-	var hash [32]byte
-
-	txCopy := *tx
-	txCopy.ID = []byte{}
-	txCopy.Signature = nil
-
-	data := struct {
-		Vin    []TXInput
-		Vout   []TXOutput
-		From   string
-		To     string
-		Amount float32
-	}{
-		Vin:    txCopy.Vin,
-		Vout:   txCopy.Vout,
-		From:   txCopy.From,
-		To:     txCopy.To,
-		Amount: txCopy.Amount,
-	}
-
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(data)
-	if err != nil {
-		log.Panic(err)
+// NewUTXOTransactionWithFeeRate creates a payment paying feePerByte DYP for
+// each byte of the signed transaction. The fee decides how many inputs are
+// needed and the inputs decide the size, so the transaction is rebuilt until
+// its fee covers its size.
+func NewUTXOTransactionWithFeeRate(privateKeyHex, from, to string, amount, feePerByte float32, opts TxOptions, bc *Blockchain) *Transaction {
+	fee := float32(0)
+	for {
+		tx := NewUTXOTransaction(privateKeyHex, from, to, amount, fee, opts, bc)
+		if want := feePerByte * float32(tx.Size()); fee < want {
+			fee = want
+			continue
+		}
+		return tx
 	}
+}
 
-	hash = crypto.Keccak256Hash(buf.Bytes())
+// Hash returns the hash of the Transaction: the Keccak-256 of its canonical
+// encoding, which leaves out the ID and the transaction level signature
+func (tx *Transaction) Hash() []byte {
+	hash := crypto.Keccak256Hash(tx.encodeForHash())
 	return hash[:]
 }
 
-// Sign signs each input of a Transaction
+// Sign signs each input of a Transaction. Inputs spending a multisig output
+// get the signature appended to their co-signatures when the key is a member.
 func (tx *Transaction) Sign(privKey *ecdsa.PrivateKey, prevTXs map[string]Transaction) {
 	if tx.IsCoinbase() {
 		return
 	}
 
 	txCopy := tx.TrimmedCopy()
+	signer := crypto.PubkeyToAddress(privKey.PublicKey)
 
 	for inID, vin := range txCopy.Vin {
-		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
-		txCopy.Vin[inID].Signature = nil
-		txCopy.Vin[inID].PubKey = []byte(prevTx.Vout[vin.Vout].Address)
-		txCopy.ID = txCopy.Hash()
-		txCopy.Vin[inID].PubKey = nil
-
-		dataHash := crypto.Keccak256Hash(txCopy.ID)
-		signature, err := crypto.Sign(dataHash.Bytes(), privKey)
+		prevOut := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		if prevOut.Multisig != nil && !prevOut.Multisig.Contains(signer.Hex()) {
+			continue
+		}
+
+		dataHash := txCopy.inputSignatureHash(inID, prevOut)
+		if prevOut.Multisig != nil && hasSigner(dataHash, tx.Vin[inID].Signatures, signer) {
+			continue
+		}
+
+		signature, err := crypto.Sign(dataHash, privKey)
 		if err != nil {
 			log.Panic(err)
 		}
 
-		tx.Vin[inID].Signature = signature
+		if prevOut.Multisig != nil {
+			tx.Vin[inID].Signatures = append(tx.Vin[inID].Signatures, signature)
+		} else {
+			tx.Vin[inID].Signature = signature
+		}
 	}
 }
 
@@ -204,16 +271,21 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 	txCopy := tx.TrimmedCopy()
 
 	for inID, vin := range tx.Vin {
-		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
-		txCopy.Vin[inID].Signature = nil
-		txCopy.Vin[inID].PubKey = []byte(prevTx.Vout[vin.Vout].Address)
-		txCopy.ID = txCopy.Hash()
-		txCopy.Vin[inID].PubKey = nil
+		prevOut := prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout]
+		if prevOut.IsData() {
+			return false // Data outputs can never be spent
+		}
+		dataHash := txCopy.inputSignatureHash(inID, prevOut)
 
-		dataHash := crypto.Keccak256Hash(txCopy.ID)
+		if prevOut.Multisig != nil {
+			if !verifyMultisig(dataHash, vin.Signatures, prevOut.Multisig) {
+				return false
+			}
+			continue
+		}
 
 		// Recover the public key from the signature
-		pubKey, err := crypto.Ecrecover(dataHash.Bytes(), vin.Signature)
+		pubKey, err := crypto.Ecrecover(dataHash, vin.Signature)
 		if err != nil {
 			return false
 		}
@@ -226,7 +298,17 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 
 		// Get the address from the public key
 		recoveredAddr := crypto.PubkeyToAddress(*sigPublicKeyECDSA)
-		expectedAddr := common.HexToAddress(string(prevTx.Vout[vin.Vout].Address))
+		expectedAddr := common.HexToAddress(string(prevOut.Address))
+
+		// HTLC outputs are signed by the recipient with the preimage, or by
+		// the sender once the timeout has passed
+		if prevOut.HTLC != nil {
+			spender, err := prevOut.HTLC.spender(vin.Preimage, tx.LockTime)
+			if err != nil {
+				return false
+			}
+			expectedAddr = common.HexToAddress(spender)
+		}
 
 		if recoveredAddr != expectedAddr {
 			return false
@@ -236,20 +318,68 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 	return true
 }
 
+// inputSignatureHash returns the digest signed for input inID. It must be
+// called on a trimmed copy. Signatures made for another chain ID recover to a
+// different signer.
+func (tx *Transaction) inputSignatureHash(inID int, prevOut TXOutput) []byte {
+	tx.Vin[inID].PubKey = []byte(prevOut.Address)
+	tx.ID = tx.Hash()
+	tx.Vin[inID].PubKey = nil
+
+	return signatureHash(tx.ID, activeNetwork.Genesis.ChainID).Bytes()
+}
+
+// hasSigner reports whether one of sigs over dataHash was made by signer
+func hasSigner(dataHash []byte, sigs [][]byte, signer common.Address) bool {
+	for _, sig := range sigs {
+		pubKey, err := crypto.SigToPub(dataHash, sig)
+		if err == nil && crypto.PubkeyToAddress(*pubKey) == signer {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureHash returns the digest signed for a transaction input. The chain ID
+// is part of the preimage (EIP-155 style) so signatures are only valid on one network.
+func signatureHash(txID []byte, chainID int64) common.Hash {
+	return crypto.Keccak256Hash(txID, IntToHex(chainID))
+}
+
 // TrimmedCopy creates a trimmed copy of Transaction to be used in signing
 func (tx *Transaction) TrimmedCopy() Transaction {
 	var inputs []TXInput
 	var outputs []TXOutput
 
 	for _, vin := range tx.Vin {
-		inputs = append(inputs, TXInput{vin.Txid, vin.Vout, nil, nil})
+		inputs = append(inputs, TXInput{Txid: vin.Txid, Vout: vin.Vout, Sequence: vin.Sequence})
 	}
 
 	for _, vout := range tx.Vout {
-		outputs = append(outputs, TXOutput{vout.Value, vout.Address})
+		outputs = append(outputs, TXOutput{
+			Value:       vout.Value,
+			Address:     vout.Address,
+			Multisig:    vout.Multisig,
+			HTLC:        vout.HTLC,
+			Data:        vout.Data,
+			TokenID:     vout.TokenID,
+			TokenAmount: vout.TokenAmount,
+		})
 	}
 
-	txCopy := Transaction{tx.ID, inputs, outputs, tx.From, tx.To, tx.Amount, tx.Fee, nil}
+	txCopy := Transaction{
+		ID:         tx.ID,
+		Vin:        inputs,
+		Vout:       outputs,
+		From:       tx.From,
+		To:         tx.To,
+		Amount:     tx.Amount,
+		Fee:        tx.Fee,
+		Signature:  nil,
+		ExtraNonce: tx.ExtraNonce,
+		LockTime:   tx.LockTime,
+		Issuance:   tx.Issuance,
+	}
 
 	return txCopy
 }
@@ -267,15 +397,117 @@ func (tx Transaction) Serialize() []byte {
 	return encoded.Bytes()
 }
 
+// Size returns the length of the serialized transaction in bytes
+func (tx Transaction) Size() int {
+	return len(tx.Serialize())
+}
+
+// coinbaseScript builds the coinbase input data: the block height followed by
+// optional miner extra data
+func coinbaseScript(height int, extraData []byte) []byte {
+	return append(IntToHex(int64(height)), extraData...)
+}
+
+// CoinbaseHeight returns the block height committed to in a coinbase transaction
+func (tx Transaction) CoinbaseHeight() (int, error) {
+	if !tx.IsCoinbase() {
+		return 0, fmt.Errorf("not a coinbase transaction")
+	}
+
+	script := tx.Vin[0].Signature
+	if len(script) < 8 {
+		return 0, fmt.Errorf("coinbase does not commit to a block height")
+	}
+
+	return int(binary.BigEndian.Uint64(script[:8])), nil
+}
+
 // IsCoinbase checks whether the transaction is coinbase
 func (tx Transaction) IsCoinbase() bool {
 	return len(tx.Vin) == 1 && len(tx.Vin[0].Txid) == 0 && tx.Vin[0].Vout == -1
 }
 
+// CheckOutputs checks that every output is well formed
+func (tx *Transaction) CheckOutputs() error {
+	dataOutputs := 0
+	for i, out := range tx.Vout {
+		if value := float64(out.Value); value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("output %d has invalid value %f", i, out.Value)
+		}
+
+		if out.IsData() {
+			if err := out.checkData(); err != nil {
+				return fmt.Errorf("output %d: %v", i, err)
+			}
+			dataOutputs++
+			continue
+		}
+
+		if out.Multisig != nil && out.HTLC != nil {
+			return fmt.Errorf("output %d has more than one lock", i)
+		}
+
+		if out.Multisig != nil {
+			if err := out.Multisig.Validate(); err != nil {
+				return fmt.Errorf("output %d: %v", i, err)
+			}
+			if !strings.EqualFold(out.Address, out.Multisig.Address()) {
+				return fmt.Errorf("output %d does not pay to its multisig address", i)
+			}
+		}
+
+		if out.HTLC != nil {
+			if err := out.HTLC.Validate(); err != nil {
+				return fmt.Errorf("output %d: %v", i, err)
+			}
+			if !strings.EqualFold(out.Address, out.HTLC.Address()) {
+				return fmt.Errorf("output %d does not pay to its HTLC address", i)
+			}
+		}
+	}
+
+	if dataOutputs > 1 {
+		return fmt.Errorf("transaction has %d data outputs, at most one is allowed", dataOutputs)
+	}
+	return tx.checkTokenOutputs()
+}
+
+// checkFee checks that tx creates no DYP: its outputs may not be worth more
+// than the inputs it spends, resolved in prevTXs, and its declared fee must be
+// what the inputs leave over. Block producers collect the declared fees, so a
+// fee the inputs do not pay would mint coins.
+func (tx *Transaction) checkFee(prevTXs map[string]Transaction) error {
+	if fee := float64(tx.Fee); fee < 0 || math.IsNaN(fee) || math.IsInf(fee, 0) {
+		return fmt.Errorf("fee %f is not a valid amount", tx.Fee)
+	}
+
+	inputs := float64(0)
+	for _, vin := range tx.Vin {
+		inputs += float64(prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout].Value)
+	}
+	outputs := float64(0)
+	for _, out := range tx.Vout {
+		outputs += float64(out.Value)
+	}
+
+	// Wallets work out change in float32, and every addition or subtraction
+	// may round by up to one float32 step of the input total
+	tolerance := float64(len(tx.Vin)+len(tx.Vout)+1) * inputs * float32Epsilon
+	paid := inputs - outputs
+	if paid < -tolerance {
+		return fmt.Errorf("outputs of %f DYP exceed inputs of %f DYP", outputs, inputs)
+	}
+	if math.Abs(float64(tx.Fee)-paid) > tolerance {
+		return fmt.Errorf("declared fee %f DYP does not match inputs minus outputs of %f DYP", tx.Fee, math.Max(paid, 0))
+	}
+
+	return nil
+}
+
 // NewTXOutput creates a new TXOutput
 func NewTXOutput(value float32, address string) *TXOutput {
 	if !common.IsHexAddress(address) {
 		log.Panic("Invalid address format")
 	}
-	return &TXOutput{value, address}
+	return &TXOutput{Value: value, Address: address}
 }
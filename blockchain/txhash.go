@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// txEncoder writes the canonical encoding transaction hashes are computed
+// over. Every field is written in a fixed order: integers as 8 byte big-endian
+// values, floats by their IEEE 754 bits, byte strings and strings prefixed by
+// their length, lists prefixed by their count and optional structs by a 0 or
+// 1 byte. Unlike gob, the result depends only on the transaction, so other
+// implementations can reproduce transaction IDs and signature hashes.
+type txEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *txEncoder) uint(v uint64) {
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (e *txEncoder) int(v int64) {
+	e.uint(uint64(v))
+}
+
+func (e *txEncoder) float(v float32) {
+	e.uint(uint64(math.Float32bits(v)))
+}
+
+func (e *txEncoder) bytes(b []byte) {
+	e.uint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *txEncoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *txEncoder) present(ok bool) bool {
+	if ok {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+	return ok
+}
+
+func (e *txEncoder) input(in *TXInput) {
+	e.bytes(in.Txid)
+	e.int(int64(in.Vout))
+	e.bytes(in.Signature)
+	e.bytes(in.PubKey)
+	e.uint(uint64(in.Sequence))
+	e.uint(uint64(len(in.Signatures)))
+	for _, sig := range in.Signatures {
+		e.bytes(sig)
+	}
+	e.bytes(in.Preimage)
+}
+
+func (e *txEncoder) output(out *TXOutput) {
+	e.float(out.Value)
+	e.string(out.Address)
+	if e.present(out.Multisig != nil) {
+		e.int(int64(out.Multisig.Threshold))
+		e.uint(uint64(len(out.Multisig.Addresses)))
+		for _, address := range out.Multisig.Addresses {
+			e.string(address)
+		}
+	}
+	if e.present(out.HTLC != nil) {
+		e.string(out.HTLC.HashType)
+		e.bytes(out.HTLC.Hash)
+		e.string(out.HTLC.Recipient)
+		e.string(out.HTLC.Sender)
+		e.int(int64(out.HTLC.Timeout))
+	}
+	e.bytes(out.Data)
+	e.string(out.TokenID)
+	e.uint(out.TokenAmount)
+}
+
+// encodeForHash returns the canonical encoding of tx without its ID and
+// transaction level signature
+func (tx *Transaction) encodeForHash() []byte {
+	var e txEncoder
+
+	e.uint(uint64(len(tx.Vin)))
+	for i := range tx.Vin {
+		e.input(&tx.Vin[i])
+	}
+	e.uint(uint64(len(tx.Vout)))
+	for i := range tx.Vout {
+		e.output(&tx.Vout[i])
+	}
+	e.string(tx.From)
+	e.string(tx.To)
+	e.float(tx.Amount)
+	e.float(tx.Fee)
+	e.uint(tx.ExtraNonce)
+	e.int(tx.LockTime)
+	if e.present(tx.Issuance != nil) {
+		e.string(tx.Issuance.Name)
+		e.string(tx.Issuance.Symbol)
+		e.uint(tx.Issuance.Supply)
+		e.string(tx.Issuance.Metadata)
+	}
+
+	return e.buf.Bytes()
+}
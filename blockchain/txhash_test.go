@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// hashTestTx covers every field of the canonical encoding
+func hashTestTx() *Transaction {
+	return &Transaction{
+		Vin: []TXInput{{
+			Txid:       []byte{1, 2, 3},
+			Vout:       1,
+			PubKey:     []byte{4},
+			Sequence:   10,
+			Signatures: [][]byte{{5}},
+			Preimage:   []byte{6},
+		}},
+		Vout: []TXOutput{
+			{Value: 1.5, Address: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+			{Value: 2, Address: "0xmultisig", Multisig: &MultisigLock{Threshold: 1, Addresses: []string{"0xa"}}},
+			{Value: 3, Address: "0xhtlc", HTLC: &HTLCLock{HashType: "sha256", Hash: []byte{7}, Recipient: "0xr", Sender: "0xs", Timeout: 20}},
+			{Data: []byte("note")},
+			{Address: "0xb", TokenID: "abc", TokenAmount: 100},
+		},
+		From:       "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		To:         "0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		Amount:     1.5,
+		Fee:        0.01,
+		ExtraNonce: 9,
+		LockTime:   100,
+		Issuance:   &TokenIssuance{Name: "Token", Symbol: "TKN", Supply: 1000, Metadata: "m"},
+	}
+}
+
+func TestTransactionHashGolden(t *testing.T) {
+	const want = "cc088ee9a08af15aae97ee654eb9609e8c627ccce6682cd96f9456b6f28b2b9e"
+
+	if got := hex.EncodeToString(hashTestTx().Hash()); got != want {
+		t.Fatalf("Hash() = %s, want %s", got, want)
+	}
+}
+
+func TestTransactionHashIgnoresIDAndSignature(t *testing.T) {
+	tx := hashTestTx()
+	hash := tx.Hash()
+
+	tx.ID = []byte{0xff}
+	tx.Signature = []byte{0xee}
+	if !bytes.Equal(tx.Hash(), hash) {
+		t.Fatal("Hash() changed with the ID or transaction signature")
+	}
+}
+
+func TestTransactionHashCoversFields(t *testing.T) {
+	hash := hashTestTx().Hash()
+
+	changes := map[string]func(*Transaction){
+		"fee":           func(tx *Transaction) { tx.Fee = 0.02 },
+		"input vout":    func(tx *Transaction) { tx.Vin[0].Vout = 2 },
+		"input sig":     func(tx *Transaction) { tx.Vin[0].Signature = []byte{1} },
+		"output value":  func(tx *Transaction) { tx.Vout[0].Value = 1.6 },
+		"multisig":      func(tx *Transaction) { tx.Vout[1].Multisig.Threshold = 2 },
+		"htlc timeout":  func(tx *Transaction) { tx.Vout[2].HTLC.Timeout = 21 },
+		"token amount":  func(tx *Transaction) { tx.Vout[4].TokenAmount = 101 },
+		"lock time":     func(tx *Transaction) { tx.LockTime = 101 },
+		"issuance":      func(tx *Transaction) { tx.Issuance = nil },
+		"moved field":   func(tx *Transaction) { tx.From, tx.To = tx.To, tx.From },
+		"data to token": func(tx *Transaction) { tx.Vout[3].Data = nil },
+	}
+	for name, change := range changes {
+		tx := hashTestTx()
+		change(tx)
+		if bytes.Equal(tx.Hash(), hash) {
+			t.Errorf("Hash() did not change with the %s", name)
+		}
+	}
+}
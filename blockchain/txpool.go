@@ -0,0 +1,41 @@
+package blockchain
+
+import "errors"
+
+// errNoTxPool is returned when transactions are submitted to a chain without
+// an attached transaction pool
+var errNoTxPool = errors.New("no transaction pool is attached to the chain")
+
+// TxPool holds validated transactions waiting to be included in a block
+type TxPool interface {
+	// Add validates tx and admits it to the pool
+	Add(tx *Transaction) error
+
+	// Transactions returns the pending transactions
+	Transactions() []*Transaction
+}
+
+// ChainListener is notified when blocks are connected to the tip of the chain.
+// There is no BlockDisconnected: the chain only ever extends its tip and
+// AddBlock refuses blocks that do not, so reorganizations are out of scope
+// and connected blocks are never undone.
+type ChainListener interface {
+	BlockConnected(block *Block)
+}
+
+// SetTxPool attaches the pool that AddTransaction and GetPendingTransactions use
+func (bc *Blockchain) SetTxPool(pool TxPool) {
+	bc.txPool = pool
+}
+
+// Subscribe registers a listener for chain tip changes
+func (bc *Blockchain) Subscribe(listener ChainListener) {
+	bc.listeners = append(bc.listeners, listener)
+}
+
+// notifyBlockConnected tells the listeners that block is the new tip
+func (bc *Blockchain) notifyBlockConnected(block *Block) {
+	for _, listener := range bc.listeners {
+		listener.BlockConnected(block)
+	}
+}
@@ -0,0 +1,247 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// xpubVersion is the BIP32 version prefix of mainnet extended public keys
+var xpubVersion = []byte{0x04, 0x88, 0xb2, 0x1e}
+
+// xpubLength is the length of a serialized extended key before the checksum
+const xpubLength = 78
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// ExtendedPublicKey is a BIP32 public key with its chain code. It derives
+// the addresses below it without any private key, so watch-only clients can
+// hand it out to find their addresses.
+type ExtendedPublicKey struct {
+	depth             byte
+	parentFingerprint []byte
+	childNumber       uint32
+	chainCode         []byte
+	key               []byte // 33 byte compressed public key
+}
+
+// ExtendedPublicKey returns the extended public key at a derivation path
+func (hw *HDWallet) ExtendedPublicKey(path []uint32) (*ExtendedPublicKey, error) {
+	if len(path) == 0 {
+		return hw.master.public(nil, 0, 0)
+	}
+
+	parent := hw.master
+	for _, index := range path[:len(path)-1] {
+		var err error
+		if parent, err = parent.child(index); err != nil {
+			return nil, err
+		}
+	}
+	last := path[len(path)-1]
+	key, err := parent.child(last)
+	if err != nil {
+		return nil, err
+	}
+
+	parentKey, err := parent.compressedPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.public(fingerprint(parentKey), byte(len(path)), last)
+}
+
+// compressedPubKey returns the compressed public key of k
+func (k *extendedKey) compressedPubKey() ([]byte, error) {
+	privateKey, err := crypto.ToECDSA(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.CompressPubkey(&privateKey.PublicKey), nil
+}
+
+// public returns the extended public key of k
+func (k *extendedKey) public(parentFingerprint []byte, depth byte, childNumber uint32) (*ExtendedPublicKey, error) {
+	key, err := k.compressedPubKey()
+	if err != nil {
+		return nil, err
+	}
+	if parentFingerprint == nil {
+		parentFingerprint = make([]byte, 4)
+	}
+	return &ExtendedPublicKey{
+		depth:             depth,
+		parentFingerprint: parentFingerprint,
+		childNumber:       childNumber,
+		chainCode:         k.chainCode,
+		key:               key,
+	}, nil
+}
+
+// fingerprint returns the first 4 bytes of HASH160 of a compressed public key
+func fingerprint(key []byte) []byte {
+	sha := sha256.Sum256(key)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return hasher.Sum(nil)[:4]
+}
+
+// ParseExtendedPublicKey decodes a base58 "xpub" string
+func ParseExtendedPublicKey(s string) (*ExtendedPublicKey, error) {
+	data, err := base58CheckDecode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != xpubLength {
+		return nil, fmt.Errorf("extended public key is %d bytes, expected %d", len(data), xpubLength)
+	}
+	if !bytes.Equal(data[:4], xpubVersion) {
+		return nil, errors.New("not an extended public key (xpub)")
+	}
+
+	key := data[45:78]
+	if _, err := crypto.DecompressPubkey(key); err != nil {
+		return nil, fmt.Errorf("invalid public key in extended key: %v", err)
+	}
+
+	return &ExtendedPublicKey{
+		depth:             data[4],
+		parentFingerprint: data[5:9],
+		childNumber:       binary.BigEndian.Uint32(data[9:13]),
+		chainCode:         data[13:45],
+		key:               key,
+	}, nil
+}
+
+// String encodes the key in the base58 "xpub" form
+func (k *ExtendedPublicKey) String() string {
+	data := make([]byte, 0, xpubLength)
+	data = append(data, xpubVersion...)
+	data = append(data, k.depth)
+	data = append(data, k.parentFingerprint...)
+	data = binary.BigEndian.AppendUint32(data, k.childNumber)
+	data = append(data, k.chainCode...)
+	data = append(data, k.key...)
+	return base58CheckEncode(data)
+}
+
+// Child derives the non-hardened child public key at index
+func (k *ExtendedPublicKey) Child(index uint32) (*ExtendedPublicKey, error) {
+	if index >= HardenedOffset {
+		return nil, errors.New("hardened children cannot be derived from a public key")
+	}
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(k.key)
+	mac.Write(binary.BigEndian.AppendUint32(nil, index))
+	sum := mac.Sum(nil)
+
+	// The child key is IL*G + K, unusable when IL >= n or the sum is infinity
+	curve := crypto.S256()
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+	parent, err := crypto.DecompressPubkey(k.key)
+	if err != nil {
+		return nil, err
+	}
+	tx, ty := curve.ScalarBaseMult(sum[:32])
+	x, y := curve.Add(tx, ty, parent.X, parent.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, fmt.Errorf("invalid child key at index %d", index)
+	}
+
+	return &ExtendedPublicKey{
+		depth:             k.depth + 1,
+		parentFingerprint: fingerprint(k.key),
+		childNumber:       index,
+		chainCode:         sum[32:],
+		key:               crypto.CompressPubkey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y}),
+	}, nil
+}
+
+// Address returns the address of the key
+func (k *ExtendedPublicKey) Address() (string, error) {
+	pubKey, err := crypto.DecompressPubkey(k.key)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
+
+// Account returns the BIP44 account of a key at m/44'/60'/account'
+func (k *ExtendedPublicKey) Account() (uint32, error) {
+	if k.depth != 3 || k.childNumber < HardenedOffset {
+		return 0, errors.New("extended key is not a BIP44 account key at m/44'/60'/account'")
+	}
+	return k.childNumber - HardenedOffset, nil
+}
+
+// base58CheckEncode encodes data followed by its double SHA-256 checksum
+func base58CheckEncode(data []byte) string {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	data = append(append([]byte{}, data...), second[:4]...)
+
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// Leading zero bytes are written as leading ones
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode decodes a base58 string and verifies its checksum
+func base58CheckDecode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i, c := range []byte(s) {
+		digit := bytes.IndexByte([]byte(base58Alphabet), c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		if digit == 0 && i == zeros {
+			zeros++
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 4 {
+		return nil, errors.New("base58 string is too short")
+	}
+
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, errors.New("invalid base58 checksum")
+	}
+	return payload, nil
+}
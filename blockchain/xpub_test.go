@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP32 test vector 1
+const vectorSeed = "000102030405060708090a0b0c0d0e0f"
+
+func TestExtendedPublicKeyVectors(t *testing.T) {
+	seed, _ := hex.DecodeString(vectorSeed)
+	hw, err := newHDWalletFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vectors := []struct {
+		path string
+		xpub string
+	}{
+		{"m/0'", "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"},
+		{"m/0'/1", "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"},
+		{"m/0'/1/2'/2", "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV"},
+	}
+	for _, v := range vectors {
+		path, err := ParseDerivationPath(v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := hw.ExtendedPublicKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := key.String(); got != v.xpub {
+			t.Errorf("%s: got %s, want %s", v.path, got, v.xpub)
+		}
+	}
+}
+
+func TestExtendedPublicKeyChild(t *testing.T) {
+	// m/0'/1 derived from the public key at m/0' alone
+	parent, err := ParseExtendedPublicKey("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := parent.Child(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"
+	if got := child.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if _, err := parent.Child(HardenedOffset); err == nil {
+		t.Error("hardened child derived from a public key")
+	}
+}
+
+func TestAccountKeyDerivesAddresses(t *testing.T) {
+	hw, err := NewHDWalletFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accountKey, err := hw.ExtendedPublicKey(AccountPath(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account, err := accountKey.Account(); err != nil || account != 0 {
+		t.Fatalf("account is %d, %v, want 0", account, err)
+	}
+
+	external, err := accountKey.Child(ExternalChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := external.Child(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := key.Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"; address != want {
+		t.Errorf("got %s, want %s", address, want)
+	}
+}
+
+func TestParseExtendedPublicKeyRejectsBadChecksum(t *testing.T) {
+	if _, err := ParseExtendedPublicKey("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnx"); err == nil {
+		t.Error("extended key with a bad checksum parsed")
+	}
+}
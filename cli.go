@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"dyp_chain/api"
+	"dyp_chain/blockchain"
+	"dyp_chain/mempool"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -12,14 +17,35 @@ import (
 // CLI responsible for processing command line arguments
 type CLI struct{}
 
+// defaultNodeURL is the HTTP API of a node running on this machine
+const defaultNodeURL = "http://localhost:8080"
+
 func (cli *CLI) printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  createblockchain -address ADDRESS - Create a blockchain and send genesis block reward to ADDRESS")
-	fmt.Println("  createwallet - Generates a new key-pair and saves it into the wallet file")
-	fmt.Println("  getbalance -address ADDRESS - Get balance of ADDRESS")
-	fmt.Println("  listaddresses - Lists all addresses from the wallet file")
+	fmt.Println("  bumpfee -txid TXID -fee FEE [-keystore DIR] [-passwordfile FILE] [-node URL] - Replace a pending transaction on a running node with one paying FEE, signed with the keystore key of its sender")
+	fmt.Println("  createblockchain - Create a blockchain from the network genesis")
+	fmt.Println("  createmultisig -threshold M -addresses A,B,C - Print the address of an M-of-N multisig")
+	fmt.Println("  deriveaddress [-account N] [-change] [-index N] [-path PATH] - Print the address and private key at a BIP44 path of a mnemonic read from the prompt")
+	fmt.Println("  discover [-account N] [-gap N] - Find the used addresses of a mnemonic read from the prompt and their balances")
+	fmt.Println("  createwallet [-keystore DIR] [-passwordfile FILE] - Generate a key-pair and save it encrypted in the keystore")
+	fmt.Println("  exportxpub [-account N] - Print the extended public key of a BIP44 account of a mnemonic read from the prompt, for watch-only discovery on a node")
+	fmt.Println("  exportkey -address ADDRESS [-keystore DIR] [-passwordfile FILE] - Decrypt and print the private key of ADDRESS")
+	fmt.Println("  fundmultisig -from FROM -threshold M -addresses A,B,C -amount AMOUNT [-keystore DIR] [-passwordfile FILE] [-node URL] - Send AMOUNT from FROM into the multisig through a running node, signing with the keystore key of FROM")
+	fmt.Println("  generate -blocks N -address ADDRESS [-admin URL] - Instantly mine N blocks on a running regtest node, paying rewards to ADDRESS")
+	fmt.Println("  getbalance -address ADDRESS - Get the confirmed, pending and spendable balance of ADDRESS")
+	fmt.Println("  newmnemonic - Generate a BIP39 mnemonic and print its first receiving address")
+	fmt.Println("  importkey [-keystore DIR] [-passwordfile FILE] - Read a private key from the prompt and save it encrypted in the keystore")
+	fmt.Println("  listaddresses [-keystore DIR] - List the addresses in the keystore")
 	fmt.Println("  printchain - Print all the blocks of the blockchain")
-	fmt.Println("  send -from FROM -to TO -amount AMOUNT - Send AMOUNT of coins from FROM address to TO")
+	fmt.Println("  spendmultisig -threshold M -addresses A,B,C -to TO -amount AMOUNT [-node URL] - Build an unsigned multisig spend and print it as hex")
+	fmt.Println("  signmultisig -tx HEX -address ADDRESS [-keystore DIR] [-passwordfile FILE] [-node URL] - Add the co-signature of the keystore key of ADDRESS to a multisig spend and print it")
+	fmt.Println("  submitmultisig -tx HEX [-node URL] - Submit a fully co-signed multisig spend to a running node")
+	fmt.Println("  send -from FROM -to TO -amount AMOUNT [-fee FEE | -conftarget N] [-locktime N] [-sequence N] [-keystore DIR] [-passwordfile FILE] [-node URL] - Send AMOUNT of coins from FROM address to TO through a running node, signing with the keystore key of FROM and estimating the fee to confirm within N blocks unless FEE is given")
+	fmt.Println()
+	fmt.Println("Commands that change the mempool go through the node at -node, " + defaultNodeURL + " by default; keys stay on this machine.")
+	fmt.Println("The keystore defaults to the keystore directory next to the chain database. Passphrases are prompted for unless -passwordfile is given.")
+	fmt.Println("Private keys are only read from the keystore, and mnemonics only from the prompt, so neither ends up in the shell history.")
+	fmt.Println("Set NETWORK (mainnet, testnet, regtest) and optionally GENESIS_FILE to choose the network.")
 }
 
 func (cli *CLI) validateArgs() {
@@ -33,30 +59,145 @@ func (cli *CLI) validateArgs() {
 func (cli *CLI) Run() {
 	cli.validateArgs()
 
+	bumpFeeCmd := flag.NewFlagSet("bumpfee", flag.ExitOnError)
 	createBlockchainCmd := flag.NewFlagSet("createblockchain", flag.ExitOnError)
+	createMultisigCmd := flag.NewFlagSet("createmultisig", flag.ExitOnError)
+	createWalletCmd := flag.NewFlagSet("createwallet", flag.ExitOnError)
+	deriveAddressCmd := flag.NewFlagSet("deriveaddress", flag.ExitOnError)
+	discoverCmd := flag.NewFlagSet("discover", flag.ExitOnError)
+	exportKeyCmd := flag.NewFlagSet("exportkey", flag.ExitOnError)
+	exportXPubCmd := flag.NewFlagSet("exportxpub", flag.ExitOnError)
+	fundMultisigCmd := flag.NewFlagSet("fundmultisig", flag.ExitOnError)
+	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
 	getBalanceCmd := flag.NewFlagSet("getbalance", flag.ExitOnError)
+	importKeyCmd := flag.NewFlagSet("importkey", flag.ExitOnError)
+	listAddressesCmd := flag.NewFlagSet("listaddresses", flag.ExitOnError)
+	newMnemonicCmd := flag.NewFlagSet("newmnemonic", flag.ExitOnError)
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
+	spendMultisigCmd := flag.NewFlagSet("spendmultisig", flag.ExitOnError)
+	signMultisigCmd := flag.NewFlagSet("signmultisig", flag.ExitOnError)
+	submitMultisigCmd := flag.NewFlagSet("submitmultisig", flag.ExitOnError)
 
-	createBlockchainAddress := createBlockchainCmd.String("address", "", "The address to send genesis block reward to")
+	bumpFeeTxID := bumpFeeCmd.String("txid", "", "ID of the pending transaction")
+	bumpFeeKeystore := bumpFeeCmd.String("keystore", "", "Keystore directory")
+	bumpFeePasswordFile := bumpFeeCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	bumpFeeFee := bumpFeeCmd.Float64("fee", 0, "New fee of the transaction")
+	bumpFeeNode := bumpFeeCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
+	createMultisigThreshold := createMultisigCmd.Int("threshold", 0, "Number of signatures required to spend")
+	createMultisigAddresses := createMultisigCmd.String("addresses", "", "Comma separated signer addresses")
+	createWalletKeystore := createWalletCmd.String("keystore", "", "Keystore directory")
+	createWalletPasswordFile := createWalletCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	deriveAddressAccount := deriveAddressCmd.Uint("account", 0, "BIP44 account")
+	deriveAddressChange := deriveAddressCmd.Bool("change", false, "Derive from the change chain instead of the receiving chain")
+	deriveAddressIndex := deriveAddressCmd.Uint("index", 0, "Address index")
+	deriveAddressPath := deriveAddressCmd.String("path", "", "Full derivation path, such as m/44'/60'/0'/0/0, overriding the other options")
+	discoverAccount := discoverCmd.Uint("account", 0, "BIP44 account")
+	discoverGap := discoverCmd.Int("gap", blockchain.DefaultGapLimit, "Consecutive unused addresses after which a chain is no longer scanned")
+	exportKeyAddress := exportKeyCmd.String("address", "", "Address whose key to print")
+	exportKeyKeystore := exportKeyCmd.String("keystore", "", "Keystore directory")
+	exportKeyPasswordFile := exportKeyCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	exportXPubAccount := exportXPubCmd.Uint("account", 0, "BIP44 account")
+	fundMultisigKeystore := fundMultisigCmd.String("keystore", "", "Keystore directory")
+	fundMultisigPasswordFile := fundMultisigCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	fundMultisigFrom := fundMultisigCmd.String("from", "", "Source wallet address")
+	fundMultisigThreshold := fundMultisigCmd.Int("threshold", 0, "Number of signatures required to spend")
+	fundMultisigAddresses := fundMultisigCmd.String("addresses", "", "Comma separated signer addresses")
+	fundMultisigAmount := fundMultisigCmd.Float64("amount", 0, "Amount to send")
+	fundMultisigFee := fundMultisigCmd.Float64("fee", 0, "Fee to send")
+	fundMultisigNode := fundMultisigCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
+	generateBlocks := generateCmd.Int("blocks", 1, "Number of blocks to generate")
+	generateAddress := generateCmd.String("address", "", "The address to send block rewards to")
+	generateAdmin := generateCmd.String("admin", "http://"+api.DefaultAdminAddr, "URL of the node's admin listener")
 	getBalanceAddress := getBalanceCmd.String("address", "", "The address to get balance for")
-	sendPrivateKey := sendCmd.String("privateKey", "", "The private key of the sender")
+	importKeyKeystore := importKeyCmd.String("keystore", "", "Keystore directory")
+	importKeyPasswordFile := importKeyCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	listAddressesKeystore := listAddressesCmd.String("keystore", "", "Keystore directory")
+	sendKeystore := sendCmd.String("keystore", "", "Keystore directory")
+	sendPasswordFile := sendCmd.String("passwordfile", "", "File whose first line is the passphrase")
 	sendFrom := sendCmd.String("from", "", "Source wallet address")
 	sendTo := sendCmd.String("to", "", "Destination wallet address")
 	sendAmount := sendCmd.Float64("amount", 0, "Amount to send")
-	sendFee := sendCmd.Float64("fee", 0, "Fee to send")
+	sendFee := sendCmd.Float64("fee", 0, "Fee to send, estimated from recent blocks when omitted")
+	sendConfTarget := sendCmd.Int("conftarget", mempool.DefaultConfirmTarget, "Blocks to confirm within when estimating the fee")
+	sendLockTime := sendCmd.Int64("locktime", 0, "Block height (or unix time >= 500000000) before which the transaction cannot be mined")
+	sendNode := sendCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
+	sendSequence := sendCmd.Uint("sequence", 0, "Relative lock: blocks the spent outputs must be buried (add 4194304 to count 512-second units)")
+	spendMultisigThreshold := spendMultisigCmd.Int("threshold", 0, "Number of signatures required to spend")
+	spendMultisigAddresses := spendMultisigCmd.String("addresses", "", "Comma separated signer addresses")
+	spendMultisigTo := spendMultisigCmd.String("to", "", "Destination wallet address")
+	spendMultisigAmount := spendMultisigCmd.Float64("amount", 0, "Amount to send")
+	spendMultisigFee := spendMultisigCmd.Float64("fee", 0, "Fee to send")
+	spendMultisigNode := spendMultisigCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
+	signMultisigTx := signMultisigCmd.String("tx", "", "Hex encoded multisig spend")
+	signMultisigAddress := signMultisigCmd.String("address", "", "Address of the co-signer")
+	signMultisigKeystore := signMultisigCmd.String("keystore", "", "Keystore directory")
+	signMultisigPasswordFile := signMultisigCmd.String("passwordfile", "", "File whose first line is the passphrase")
+	signMultisigNode := signMultisigCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
+	submitMultisigTx := submitMultisigCmd.String("tx", "", "Hex encoded multisig spend")
+	submitMultisigNode := submitMultisigCmd.String("node", defaultNodeURL, "URL of the node's HTTP API")
 
 	switch os.Args[1] {
+	case "bumpfee":
+		err := bumpFeeCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "createblockchain":
 		err := createBlockchainCmd.Parse(os.Args[2:])
 		if err != nil {
 			log.Panic(err)
 		}
+	case "createwallet":
+		err := createWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "deriveaddress":
+		err := deriveAddressCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "discover":
+		err := discoverCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "exportkey":
+		err := exportKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "exportxpub":
+		err := exportXPubCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "generate":
+		err := generateCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "getbalance":
 		err := getBalanceCmd.Parse(os.Args[2:])
 		if err != nil {
 			log.Panic(err)
 		}
+	case "importkey":
+		err := importKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "listaddresses":
+		err := listAddressesCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "newmnemonic":
+		err := newMnemonicCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "printchain":
 		err := printChainCmd.Parse(os.Args[2:])
 		if err != nil {
@@ -67,20 +208,113 @@ func (cli *CLI) Run() {
 		if err != nil {
 			log.Panic(err)
 		}
+	case "createmultisig":
+		err := createMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "fundmultisig":
+		err := fundMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "spendmultisig":
+		err := spendMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "signmultisig":
+		err := signMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "submitmultisig":
+		err := submitMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	default:
 		cli.printUsage()
 		os.Exit(1)
 	}
 
+	if bumpFeeCmd.Parsed() {
+		if *bumpFeeTxID == "" || *bumpFeeFee <= 0 {
+			bumpFeeCmd.Usage()
+			os.Exit(1)
+		}
+		cli.bumpFee(strings.TrimRight(*bumpFeeNode, "/"), *bumpFeeTxID, float32(*bumpFeeFee), *bumpFeeKeystore, *bumpFeePasswordFile)
+	}
+
 	if createBlockchainCmd.Parsed() {
-		if *createBlockchainAddress == "" {
-			createBlockchainCmd.Usage()
+		cli.createBlockchain()
+	}
+
+	if createWalletCmd.Parsed() {
+		cli.createWallet(*createWalletKeystore, *createWalletPasswordFile)
+	}
+
+	if exportKeyCmd.Parsed() {
+		if *exportKeyAddress == "" {
+			exportKeyCmd.Usage()
 			os.Exit(1)
 		}
-		if !common.IsHexAddress(*createBlockchainAddress) {
+		if !common.IsHexAddress(*exportKeyAddress) {
 			log.Panic("ERROR: Invalid address format")
 		}
-		cli.createBlockchain(*createBlockchainAddress)
+		cli.exportKey(*exportKeyKeystore, *exportKeyPasswordFile, *exportKeyAddress)
+	}
+
+	if importKeyCmd.Parsed() {
+		cli.importKey(*importKeyKeystore, *importKeyPasswordFile)
+	}
+
+	if listAddressesCmd.Parsed() {
+		cli.listAddresses(*listAddressesKeystore)
+	}
+
+	if deriveAddressCmd.Parsed() {
+		path := blockchain.AddressPath(uint32(*deriveAddressAccount), blockchain.ExternalChain, uint32(*deriveAddressIndex))
+		if *deriveAddressChange {
+			path = blockchain.AddressPath(uint32(*deriveAddressAccount), blockchain.ChangeChain, uint32(*deriveAddressIndex))
+		}
+		if *deriveAddressPath != "" {
+			var err error
+			if path, err = blockchain.ParseDerivationPath(*deriveAddressPath); err != nil {
+				log.Panic(err)
+			}
+		}
+		mnemonic, passphrase := readMnemonic()
+		cli.deriveAddress(mnemonic, passphrase, path)
+	}
+
+	if discoverCmd.Parsed() {
+		if *discoverGap <= 0 || *discoverGap > blockchain.MaxGapLimit {
+			discoverCmd.Usage()
+			os.Exit(1)
+		}
+		mnemonic, passphrase := readMnemonic()
+		cli.discover(mnemonic, passphrase, uint32(*discoverAccount), *discoverGap)
+	}
+
+	if exportXPubCmd.Parsed() {
+		mnemonic, passphrase := readMnemonic()
+		cli.exportXPub(mnemonic, passphrase, uint32(*exportXPubAccount))
+	}
+
+	if newMnemonicCmd.Parsed() {
+		cli.newMnemonic()
+	}
+
+	if generateCmd.Parsed() {
+		if *generateAddress == "" || *generateBlocks <= 0 {
+			generateCmd.Usage()
+			os.Exit(1)
+		}
+		if !common.IsHexAddress(*generateAddress) {
+			log.Panic("ERROR: Invalid address format")
+		}
+		cli.generate(*generateAdmin, *generateBlocks, *generateAddress)
 	}
 
 	if getBalanceCmd.Parsed() {
@@ -99,7 +333,7 @@ func (cli *CLI) Run() {
 	}
 
 	if sendCmd.Parsed() {
-		if *sendPrivateKey == "" || *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
+		if *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
 			sendCmd.Usage()
 			os.Exit(1)
 		}
@@ -109,6 +343,73 @@ func (cli *CLI) Run() {
 		if !common.IsHexAddress(*sendTo) {
 			log.Panic("ERROR: Invalid destination address format")
 		}
-		cli.send(*sendPrivateKey, *sendFrom, *sendTo, float32(*sendAmount), float32(*sendFee))
+		if *sendConfTarget < 1 || *sendConfTarget > mempool.MaxConfirmTarget {
+			log.Panicf("ERROR: conftarget must be between 1 and %d", mempool.MaxConfirmTarget)
+		}
+		opts := blockchain.TxOptions{
+			LockTime: *sendLockTime,
+			Sequence: uint32(*sendSequence),
+		}
+
+		var fee *float32
+		sendCmd.Visit(func(f *flag.Flag) {
+			if f.Name == "fee" {
+				value := float32(*sendFee)
+				fee = &value
+			}
+		})
+		wallet := unlockKey(*sendKeystore, *sendPasswordFile, *sendFrom)
+		cli.send(strings.TrimRight(*sendNode, "/"), wallet, *sendTo, float32(*sendAmount), fee, *sendConfTarget, opts)
+	}
+
+	if createMultisigCmd.Parsed() {
+		if *createMultisigThreshold <= 0 || *createMultisigAddresses == "" {
+			createMultisigCmd.Usage()
+			os.Exit(1)
+		}
+		cli.createMultisig(*createMultisigThreshold, *createMultisigAddresses)
+	}
+
+	if fundMultisigCmd.Parsed() {
+		if *fundMultisigFrom == "" || *fundMultisigThreshold <= 0 || *fundMultisigAddresses == "" || *fundMultisigAmount <= 0 {
+			fundMultisigCmd.Usage()
+			os.Exit(1)
+		}
+		if !common.IsHexAddress(*fundMultisigFrom) {
+			log.Panic("ERROR: Invalid source address format")
+		}
+		wallet := unlockKey(*fundMultisigKeystore, *fundMultisigPasswordFile, *fundMultisigFrom)
+		cli.fundMultisig(strings.TrimRight(*fundMultisigNode, "/"), wallet, *fundMultisigThreshold, *fundMultisigAddresses, float32(*fundMultisigAmount), float32(*fundMultisigFee))
+	}
+
+	if spendMultisigCmd.Parsed() {
+		if *spendMultisigThreshold <= 0 || *spendMultisigAddresses == "" || *spendMultisigTo == "" || *spendMultisigAmount <= 0 {
+			spendMultisigCmd.Usage()
+			os.Exit(1)
+		}
+		if !common.IsHexAddress(*spendMultisigTo) {
+			log.Panic("ERROR: Invalid destination address format")
+		}
+		cli.spendMultisig(strings.TrimRight(*spendMultisigNode, "/"), *spendMultisigThreshold, *spendMultisigAddresses, *spendMultisigTo, float32(*spendMultisigAmount), float32(*spendMultisigFee))
+	}
+
+	if signMultisigCmd.Parsed() {
+		if *signMultisigTx == "" || *signMultisigAddress == "" {
+			signMultisigCmd.Usage()
+			os.Exit(1)
+		}
+		if !common.IsHexAddress(*signMultisigAddress) {
+			log.Panic("ERROR: Invalid address format")
+		}
+		wallet := unlockKey(*signMultisigKeystore, *signMultisigPasswordFile, *signMultisigAddress)
+		cli.signMultisig(strings.TrimRight(*signMultisigNode, "/"), *signMultisigTx, wallet)
+	}
+
+	if submitMultisigCmd.Parsed() {
+		if *submitMultisigTx == "" {
+			submitMultisigCmd.Usage()
+			os.Exit(1)
+		}
+		cli.submitMultisig(strings.TrimRight(*submitMultisigNode, "/"), *submitMultisigTx)
 	}
 }
@@ -1,33 +1,80 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 
+	"dyp_chain/api"
 	"dyp_chain/blockchain"
+	"dyp_chain/mempool"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func (cli *CLI) createBlockchain(address string) {
-	if !common.IsHexAddress(address) {
-		log.Panic("ERROR: Address is not valid")
-	}
-	bc := blockchain.CreateBlockchain(address)
+func (cli *CLI) createBlockchain() {
+	bc := blockchain.CreateBlockchain(blockchain.ActiveNetwork().Genesis)
 	defer bc.DB.Close()
 
 	fmt.Println("Done!")
 }
 
+func (cli *CLI) generate(admin string, blocks int, address string) {
+	// The running node holds the database lock and the mempool, so blocks
+	// are generated through its admin listener
+	var resp api.GenerateResponse
+	err := postJSON(admin+"/generate", api.GenerateRequest{Blocks: blocks, Address: address}, &resp)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for _, hash := range resp.BlockHashes {
+		fmt.Println(hash)
+	}
+	fmt.Printf("Generated %d blocks, height is now %d\n", len(resp.BlockHashes), resp.Height)
+}
+
+// bumpFee has the node rebuild a pending transaction paying fee, then signs
+// the replacement with the key of its sender and submits it
+func (cli *CLI) bumpFee(node, txID string, fee float32, keystoreDir, passwordFile string) {
+	var built api.BumpFeeResponse
+	if err := postJSON(node+"/transaction/bumpfee", api.BumpFeeRequest{TxID: txID, Fee: fee}, &built); err != nil {
+		log.Panic(err)
+	}
+
+	tx := decodeTx(built.Tx)
+	if !strings.EqualFold(tx.From, built.From) || tx.Fee < fee {
+		log.Panic("ERROR: The node built a different replacement than requested")
+	}
+
+	resp := submitSigned(node, tx, unlockKey(keystoreDir, passwordFile, tx.From))
+	fmt.Printf("Success! Replaced transaction %s with %s paying %f DYP.\n", txID, resp.TxID, resp.Fee)
+}
+
 func (cli *CLI) getBalance(address string) {
 	if !common.IsHexAddress(address) {
 		log.Panic("ERROR: Address is not valid")
 	}
 	bc := blockchain.NewBlockchain()
 	defer bc.DB.Close()
+	pool := mempool.New(bc)
+	if _, _, err := pool.Load(dataFile("mempool")); err != nil {
+		log.Printf("Failed to load mempool: %v", err)
+	}
 
-	balance := bc.GetBalance(address)
-	fmt.Printf("Balance of '%s': %f\n", address, balance)
+	balance := bc.GetPendingBalance(address)
+	fmt.Printf("Balance of '%s': %f\n", address, balance.Confirmed)
+	fmt.Printf("  Pending incoming: %f\n", balance.PendingIncoming)
+	fmt.Printf("  Pending outgoing: %f\n", balance.PendingOutgoing)
+	fmt.Printf("  Spendable:        %f\n", balance.Spendable)
 }
 
 func (cli *CLI) printChain() {
@@ -43,8 +90,7 @@ func (cli *CLI) printChain() {
 		fmt.Printf("Height: %d\n", block.Height)
 		fmt.Printf("Timestamp: %d\n", block.Timestamp)
 		fmt.Printf("Prev. block: %x\n", block.PrevBlockHash)
-		pow := blockchain.NewProofOfWork(block)
-		fmt.Printf("PoW: %t\n\n", pow.Validate())
+		fmt.Printf("Seal: %t\n\n", bc.Engine().VerifyHeader(bc, &block.BlockHeader) == nil)
 
 		for _, tx := range block.Transactions {
 			fmt.Printf("Transaction %x:\n", tx.ID)
@@ -60,18 +106,357 @@ func (cli *CLI) printChain() {
 	}
 }
 
-func (cli *CLI) send(privateKey, from, to string, amount, fee float32) {
-	if !common.IsHexAddress(from) {
-		log.Panic("ERROR: Sender address is not valid")
+// send builds a payment on the node, signs it with wallet and submits it.
+// Without a fee the node pays the rate estimated to confirm within
+// confTarget blocks.
+func (cli *CLI) send(node string, wallet *blockchain.Wallet, to string, amount float32, fee *float32, confTarget int, opts blockchain.TxOptions) {
+	from := wallet.GetAddress()
+	req := api.BuildTransactionRequest{
+		SendRequest: api.SendRequest{
+			FromAddress: from,
+			ToAddress:   to,
+			Amount:      amount,
+			Fee:         fee,
+			ConfTarget:  confTarget,
+			LockTime:    opts.LockTime,
+			Sequence:    opts.Sequence,
+		},
+		PublicKey: hex.EncodeToString(wallet.PublicKey),
 	}
-	if !common.IsHexAddress(to) {
-		log.Panic("ERROR: Recipient address is not valid")
+	var built api.BuildTransactionResponse
+	if err := postJSON(node+"/transaction/build", req, &built); err != nil {
+		log.Panic(err)
+	}
+
+	tx := decodeTx(built.Tx)
+	checkPayment(tx, from, to, amount)
+	resp := submitSigned(node, tx, wallet)
+	fmt.Printf("Success! Transaction %s added to mempool with a fee of %f.\n", resp.TxID, resp.Fee)
+}
+
+func (cli *CLI) createMultisig(threshold int, addresses string) {
+	lock := parseMultisigLock(threshold, addresses)
+	fmt.Printf("Multisig address: %s (%d of %d)\n", lock.Address(), lock.Threshold, len(lock.Addresses))
+}
+
+func (cli *CLI) fundMultisig(node string, wallet *blockchain.Wallet, threshold int, addresses string, amount, fee float32) {
+	lock := parseMultisigLock(threshold, addresses)
+	from := wallet.GetAddress()
+
+	req := api.MultisigFundRequest{
+		MultisigRequest: api.MultisigRequest{Threshold: lock.Threshold, Addresses: lock.Addresses},
+		FromAddress:     from,
+		Amount:          amount,
+		Fee:             fee,
+		PublicKey:       hex.EncodeToString(wallet.PublicKey),
+	}
+	var built api.MultisigFundResponse
+	if err := postJSON(node+"/multisig/fund", req, &built); err != nil {
+		log.Panic(err)
+	}
+
+	tx := decodeTx(built.Tx)
+	checkPayment(tx, from, lock.Address(), amount)
+	resp := submitSigned(node, tx, wallet)
+	fmt.Printf("Success! Funded multisig %s, transaction %s added to mempool.\n", lock.Address(), resp.TxID)
+}
+
+func (cli *CLI) spendMultisig(node string, threshold int, addresses, to string, amount, fee float32) {
+	lock := parseMultisigLock(threshold, addresses)
+
+	req := api.MultisigSpendRequest{
+		MultisigRequest: api.MultisigRequest{Threshold: lock.Threshold, Addresses: lock.Addresses},
+		ToAddress:       to,
+		Amount:          amount,
+		Fee:             fee,
+	}
+	var resp api.MultisigTxResponse
+	if err := postJSON(node+"/multisig/spend", req, &resp); err != nil {
+		log.Panic(err)
+	}
+
+	checkPayment(decodeTx(resp.Tx), lock.Address(), to, amount)
+	fmt.Println(resp.Tx)
+}
+
+// signMultisig co-signs a multisig spend with wallet. The digests are
+// computed from the transaction itself, the node only checks and adds the
+// signatures.
+func (cli *CLI) signMultisig(node, encoded string, wallet *blockchain.Wallet) {
+	tx := decodeTx(encoded)
+
+	req := api.MultisigSignRequest{Tx: encoded, Signatures: signHashes(tx.SignatureHashes(tx.From), wallet)}
+	var resp api.MultisigTxResponse
+	if err := postJSON(node+"/multisig/sign", req, &resp); err != nil {
+		log.Panic(err)
+	}
+
+	if resp.Complete {
+		fmt.Println("Transaction is fully signed.")
+	}
+	fmt.Println(resp.Tx)
+}
+
+func (cli *CLI) submitMultisig(node, encoded string) {
+	var resp api.SubmitTransactionResponse
+	if err := postJSON(node+"/multisig/submit", api.MultisigSubmitRequest{Tx: encoded}, &resp); err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Success! Transaction %s added to mempool.\n", resp.TxID)
+}
+
+// checkPayment makes sure a transaction built by the node pays amount to to
+// and sends any change back to from, before anyone signs it
+func checkPayment(tx *blockchain.Transaction, from, to string, amount float32) {
+	if !strings.EqualFold(tx.From, from) || len(tx.Vout) == 0 || !strings.EqualFold(tx.Vout[0].Address, to) || tx.Vout[0].Value != amount {
+		log.Panic("ERROR: The node built a different payment than requested")
+	}
+	for _, out := range tx.Vout[1:] {
+		if !strings.EqualFold(out.Address, from) {
+			log.Panic("ERROR: The node built a transaction paying an unexpected address")
+		}
+	}
+}
+
+// submitSigned signs every input of tx, which all spend outputs of the
+// wallet's address, and submits it to the node
+func submitSigned(node string, tx *blockchain.Transaction, wallet *blockchain.Wallet) api.SubmitTransactionResponse {
+	req := api.SubmitTransactionRequest{
+		Tx:         hex.EncodeToString(tx.Serialize()),
+		Signatures: signHashes(tx.SignatureHashes(wallet.GetAddress()), wallet),
+	}
+	var resp api.SubmitTransactionResponse
+	if err := postJSON(node+"/transaction/submit", req, &resp); err != nil {
+		log.Panic(err)
+	}
+	return resp
+}
+
+// signHashes signs each digest with the key of wallet and hex encodes the
+// signatures
+func signHashes(hashes [][]byte, wallet *blockchain.Wallet) []string {
+	signatures := make([]string, len(hashes))
+	for i, hash := range hashes {
+		sig, err := crypto.Sign(hash, wallet.PrivateKey)
+		if err != nil {
+			log.Panic(err)
+		}
+		signatures[i] = hex.EncodeToString(sig)
+	}
+	return signatures
+}
+
+func parseMultisigLock(threshold int, addresses string) *blockchain.MultisigLock {
+	lock, err := blockchain.NewMultisigLock(threshold, strings.Split(addresses, ","))
+	if err != nil {
+		log.Panic(err)
+	}
+	return lock
+}
+
+func decodeTx(encoded string) *blockchain.Transaction {
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		log.Panic("ERROR: Transaction is not valid hex")
+	}
+	tx, err := blockchain.DeserializeTransaction(data)
+	if err != nil {
+		log.Panic(err)
+	}
+	return tx
+}
+
+func (cli *CLI) newMnemonic() {
+	mnemonic, err := blockchain.NewMnemonic()
+	if err != nil {
+		log.Panic(err)
+	}
+	hd, err := blockchain.NewHDWalletFromMnemonic(mnemonic, "")
+	if err != nil {
+		log.Panic(err)
+	}
+	wallet, err := hd.DeriveAddress(0, blockchain.ExternalChain, 0)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Mnemonic: %s\n", mnemonic)
+	fmt.Printf("First address (%s): %s\n", blockchain.FormatDerivationPath(blockchain.AddressPath(0, blockchain.ExternalChain, 0)), wallet.GetAddress())
+	fmt.Println("Write the mnemonic down, it is the only backup of every address it derives.")
+}
+
+func (cli *CLI) deriveAddress(mnemonic, passphrase string, path []uint32) {
+	hd, err := blockchain.NewHDWalletFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallet, err := hd.Derive(path)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Path: %s\n", blockchain.FormatDerivationPath(path))
+	fmt.Printf("Address: %s\n", wallet.GetAddress())
+	fmt.Printf("Private key: %s\n", wallet.GetPrivateKey())
+}
+
+func (cli *CLI) exportXPub(mnemonic, passphrase string, account uint32) {
+	hd, err := blockchain.NewHDWalletFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	path := blockchain.AccountPath(account)
+	xpub, err := hd.ExtendedPublicKey(path)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Path: %s\n", blockchain.FormatDerivationPath(path))
+	fmt.Printf("Extended public key: %s\n", xpub)
+}
+
+func (cli *CLI) discover(mnemonic, passphrase string, account uint32, gapLimit int) {
+	hd, err := blockchain.NewHDWalletFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		log.Panic(err)
 	}
 
 	bc := blockchain.NewBlockchain()
 	defer bc.DB.Close()
+	pool := mempool.New(bc)
+	if _, _, err := pool.Load(dataFile("mempool")); err != nil {
+		log.Printf("Failed to load mempool: %v", err)
+	}
+
+	found, err := hd.Discover(bc, account, gapLimit)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	total := float32(0)
+	for _, addr := range found {
+		fmt.Printf("%-20s %s confirmed %f spendable %f\n", addr.Path, addr.Address, addr.Balance.Confirmed, addr.Balance.Spendable)
+		total += addr.Balance.Spendable
+	}
+	fmt.Printf("Found %d used addresses in account %d, %f spendable in total\n", len(found), account, total)
+}
+
+func (cli *CLI) createWallet(keystoreDir, passwordFile string) {
+	ks := openKeystore(keystoreDir)
+	passphrase := readPassphrase(passwordFile, true)
+
+	wallet, err := ks.NewAccount(passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Your new address: %s\n", wallet.GetAddress())
+	fmt.Printf("Its key is saved encrypted in %s\n", ks.Dir)
+}
+
+func (cli *CLI) importKey(keystoreDir, passwordFile string) {
+	ks := openKeystore(keystoreDir)
+	privateKey := readSecret("Private key: ")
+	passphrase := readPassphrase(passwordFile, true)
+
+	wallet, err := ks.Import(privateKey, passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Printf("Imported address %s\n", wallet.GetAddress())
+}
+
+func (cli *CLI) exportKey(keystoreDir, passwordFile, address string) {
+	wallet := unlockKey(keystoreDir, passwordFile, address)
+	fmt.Printf("Private key of %s: %s\n", wallet.GetAddress(), wallet.GetPrivateKey())
+}
+
+func (cli *CLI) listAddresses(keystoreDir string) {
+	addresses, err := openKeystore(keystoreDir).Addresses()
+	if err != nil {
+		log.Panic(err)
+	}
+	for _, address := range addresses {
+		fmt.Println(address)
+	}
+}
+
+// stdin is shared by the prompts so lines read ahead are not lost
+var stdin = bufio.NewReader(os.Stdin)
+
+// openKeystore opens dir, or the default keystore when dir is empty
+func openKeystore(dir string) *blockchain.Keystore {
+	if dir == "" {
+		dir = blockchain.DefaultKeystoreDir()
+	}
+	ks, err := blockchain.NewKeystore(dir)
+	if err != nil {
+		log.Panic(err)
+	}
+	return ks
+}
+
+// unlockKey decrypts the keystore key of address
+func unlockKey(keystoreDir, passwordFile, address string) *blockchain.Wallet {
+	ks := openKeystore(keystoreDir)
+	wallet, err := ks.Unlock(address, readPassphrase(passwordFile, false))
+	if err != nil {
+		log.Panic(err)
+	}
+	return wallet
+}
 
-	tx := blockchain.NewUTXOTransaction(privateKey, from, to, amount, fee, bc)
-	bc.AddTransaction(tx) // Add to mempool instead of directly creating a block
-	fmt.Println("Success! Transaction added to mempool.")
+// readLine prompts on stderr and reads a line from stdin
+func readLine(prompt string) string {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		log.Panic("ERROR: no input")
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// readMnemonic prompts for a BIP39 mnemonic and its optional passphrase, so
+// neither shows up in the shell history or the process list
+func readMnemonic() (mnemonic, passphrase string) {
+	mnemonic = strings.Join(strings.Fields(readSecret("Mnemonic: ")), " ")
+	passphrase = readSecret("BIP39 passphrase (empty for none): ")
+	return mnemonic, passphrase
+}
+
+// readPassphrase returns the first line of passwordFile, or prompts for the
+// passphrase, twice when confirm is set
+func readPassphrase(passwordFile string, confirm bool) string {
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			log.Panic(err)
+		}
+		return strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r")
+	}
+
+	passphrase := readSecret("Passphrase: ")
+	if confirm && readSecret("Repeat passphrase: ") != passphrase {
+		log.Panic("ERROR: Passphrases do not match")
+	}
+	return passphrase
+}
+
+// postJSON sends req as JSON to url on a running node and decodes the
+// response into resp. Errors reported by the node are returned as is.
+func postJSON(url string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach the node: %v", err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(r.Body, 4096))
+		return fmt.Errorf("node refused the request: %s", strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(r.Body).Decode(resp)
 }
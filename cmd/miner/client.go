@@ -2,12 +2,8 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
 	"log"
-	"math"
-	"math/big"
 	"os"
 	"os/signal"
 	"strings"
@@ -15,10 +11,9 @@ import (
 	"syscall"
 	"time"
 
+	"dyp_chain/blockchain"
 	pb "dyp_chain/proto"
 
-	"bytes"
-
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -60,102 +55,109 @@ func (c *MiningClient) StopMining() {
 	}
 }
 
-// performProofOfWork performs the mining computation locally
-func (c *MiningClient) performProofOfWork(block *pb.Block, difficulty int32) (int32, []byte) {
-	var hashInt big.Int
-	target := big.NewInt(1)
-	target.Lsh(target, uint(256-difficulty))
-
-	nonce := int32(0)
-	maxNonce := math.MaxInt32
+// performProofOfWork seals the block template locally with the proof-of-work
+// engine. When the nonce space is exhausted the coinbase extranonce is rolled,
+// which changes the merkle root, and the search starts over. The template is
+// updated in place with the final transactions.
+func (c *MiningClient) performProofOfWork(template *pb.Block) (uint64, []byte) {
+	block := &blockchain.Block{
+		BlockHeader: blockchain.BlockHeader{
+			Version:       template.Version,
+			PrevBlockHash: template.PrevBlockHash,
+			Timestamp:     template.Timestamp,
+			Bits:          int(template.Bits),
+			Height:        int(template.Height),
+		},
+	}
+	for _, tx := range template.Transactions {
+		block.Transactions = append(block.Transactions, pb.TransactionFromProto(tx))
+	}
+	block.MerkleRoot = block.HashTransactions()
 
 	log.Printf("[Miner] Starting proof of work: Height=%d, Difficulty=%d, PrevHash=%x",
-		block.Height, difficulty, block.PrevBlockHash)
+		block.Height, block.Bits, block.PrevBlockHash)
+	log.Printf("[Miner] Mining block with %d real transactions", len(block.Transactions)-1)
 
-	realTxCount := 0
-	for _, tx := range block.Transactions {
-		if tx.From != "coinbase" {
-			realTxCount++
+	done := make(chan struct{})
+	defer close(done)
+	stop := c.watchChainTip(block.Height, done)
+	engine := blockchain.NewPowEngine(blockchain.ActiveNetwork())
+	startTime := time.Now()
+
+	for {
+		err := engine.Seal(nil, block, stop)
+		if err == nil {
+			break
+		}
+		if err != blockchain.ErrNonceSpaceExhausted || !rollExtraNonce(block) {
+			log.Printf("[Miner] Mining attempt stopped: %v", err)
+			return 0, nil
 		}
 	}
-	log.Printf("[Miner] Mining block with %d real transactions", realTxCount)
 
-	startTime := time.Now()
-	hashesComputed := 0
+	log.Printf("[Miner] Found solution! Nonce=%d, Hash=%x, Time=%.2fs",
+		block.Nonce, block.Hash, time.Since(startTime).Seconds())
 
-	for nonce < int32(maxNonce) {
-		select {
-		case <-c.stopMining:
-			log.Printf("[Miner] Mining operation cancelled")
-			return 0, nil
-		default:
-			data := prepareData(block, nonce, difficulty)
-			hash := sha256.Sum256(data)
-			hashInt.SetBytes(hash[:])
-
-			hashesComputed++
-			if hashesComputed%1000000 == 0 {
-				// Check blockchain status to see if we should continue mining
+	for i, tx := range block.Transactions {
+		template.Transactions[i] = pb.TransactionToProto(tx)
+	}
+	return block.Nonce, block.Hash
+}
+
+// watchChainTip returns a channel that is closed once mining is cancelled or
+// the node reports a block at height, making the current work stale. It stops
+// watching when done is closed.
+func (c *MiningClient) watchChainTip(height int, done <-chan struct{}) <-chan struct{} {
+	c.mu.Lock()
+	cancelled := c.stopMining
+	c.mu.Unlock()
+
+	stale := make(chan struct{})
+	go func() {
+		defer close(stale)
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-cancelled:
+				return
+			case <-done:
+				return
+			case <-ticker.C:
 				status, err := c.client.GetBlockchainStatus(context.Background(), &pb.BlockchainStatusRequest{})
-				if err == nil && int(status.Height) >= int(block.Height) {
+				if err == nil && int(status.Height) >= height {
 					log.Printf("[Miner] Stopping mining - new block already found at height %d", status.Height)
-					return 0, nil
+					return
 				}
-
 			}
-
-			if hashInt.Cmp(target) == -1 {
-				elapsed := time.Since(startTime)
-				hashrate := float64(hashesComputed) / elapsed.Seconds()
-				log.Printf("[Miner] Found solution! Nonce=%d, Hash=%x, Time=%.2fs, Hashrate=%.2f H/s",
-					nonce, hash, elapsed.Seconds(), hashrate)
-				return nonce, hash[:]
-			}
-
-			nonce++
 		}
-	}
-	log.Printf("[Miner] Failed to find solution within nonce range")
-	return nonce, nil
+	}()
+
+	return stale
 }
 
-// prepareData prepares block data for hashing
-func prepareData(block *pb.Block, nonce int32, difficulty int32) []byte {
-	// Hash transactions
-	var txHashes [][]byte
-	var txHash [32]byte
+// rollExtraNonce increments the extranonce of the block's coinbase and
+// recomputes its transaction ID and the merkle root so the block gets a fresh
+// nonce space
+func rollExtraNonce(block *blockchain.Block) bool {
 	for _, tx := range block.Transactions {
-		txHashes = append(txHashes, tx.TransactionId)
+		if !tx.IsCoinbase() {
+			continue
+		}
+
+		tx.ExtraNonce++
+		tx.ID = tx.Hash()
+		block.MerkleRoot = block.HashTransactions()
+		log.Printf("[Miner] Nonce space exhausted, rolled extranonce to %d", tx.ExtraNonce)
+		return true
 	}
-	txHash = sha256.Sum256(bytes.Join(txHashes, []byte{}))
-
-	// Convert numbers to hex format
-	timestamp := make([]byte, 8)
-	binary.BigEndian.PutUint64(timestamp, uint64(block.Timestamp))
-
-	targetBits := make([]byte, 8)
-	binary.BigEndian.PutUint64(targetBits, uint64(difficulty)) // Use dynamic difficulty from template
-
-	nonceBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(nonceBytes, uint64(nonce))
-
-	// Join all data in the correct order
-	data := bytes.Join(
-		[][]byte{
-			block.PrevBlockHash,
-			txHash[:],
-			timestamp,
-			targetBits,
-			nonceBytes,
-		},
-		[]byte{},
-	)
 
-	return data
+	return false
 }
 
 // StartMining starts the mining operation
-func (c *MiningClient) StartMining(minerAddress string) {
+func (c *MiningClient) StartMining(minerAddress, coinbaseData string) {
 	log.Printf("[Miner] Starting mining operations for address: %s", minerAddress)
 
 	// Create a channel to handle graceful shutdown
@@ -194,6 +196,7 @@ func (c *MiningClient) StartMining(minerAddress string) {
 				// Get block template
 				template, err := c.client.GetBlockTemplate(ctx, &pb.BlockTemplateRequest{
 					MinerAddress: minerAddress,
+					CoinbaseData: coinbaseData,
 				})
 				if err != nil {
 					if strings.Contains(err.Error(), "block size exceeds maximum") {
@@ -218,7 +221,7 @@ func (c *MiningClient) StartMining(minerAddress string) {
 					template.Block.Height, template.Block.PrevBlockHash, realTxInTemplate)
 
 				// Perform proof of work locally
-				nonce, blockHash := c.performProofOfWork(template.Block, template.Difficulty)
+				nonce, blockHash := c.performProofOfWork(template.Block)
 				if blockHash == nil {
 					log.Printf("[Miner] Mining attempt cancelled or failed, retrying with new template")
 					continue
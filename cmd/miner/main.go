@@ -8,6 +8,7 @@ import (
 func main() {
 	serverAddr := flag.String("server", "localhost:50051", "Mining server address")
 	minerAddr := flag.String("miner", "", "Miner's wallet address")
+	coinbaseData := flag.String("coinbase-data", "", "Optional extra data to include in mined coinbase transactions (max 64 bytes)")
 	flag.Parse()
 
 	if *minerAddr == "" {
@@ -21,5 +22,5 @@ func main() {
 	defer client.Close()
 
 	log.Printf("Starting mining operations for address: %s", *minerAddr)
-	client.StartMining(*minerAddr)
+	client.StartMining(*minerAddr, *coinbaseData)
 }
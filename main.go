@@ -3,10 +3,17 @@ package main
 import (
 	"dyp_chain/api"
 	"dyp_chain/blockchain"
+	"dyp_chain/mempool"
 	pb "dyp_chain/proto"
+	"flag"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
@@ -14,29 +21,80 @@ import (
 
 const port = ":50051"
 
+// mempoolSaveInterval is how often pending transactions are saved to disk
+const mempoolSaveInterval = time.Minute
+
 func main() {
 	// Try to load .env file but don't fail if it doesn't exist
 	_ = godotenv.Load()
 
-	// Check if GENESIS_ADDRESS is set
-	genesisAddr := os.Getenv("GENESIS_ADDRESS")
-	if genesisAddr == "" {
-		log.Fatal("GENESIS_ADDRESS environment variable is required")
+	// Subcommands such as createblockchain or getbalance go to the CLI, which
+	// takes its network from the environment
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		selectNetwork(os.Getenv("NETWORK"), os.Getenv("GENESIS_FILE"))
+		cli := CLI{}
+		cli.Run()
+		return
 	}
 
+	networkName := flag.String("network", os.Getenv("NETWORK"), "Network to run on: mainnet, testnet or regtest")
+	genesisFile := flag.String("genesis", os.Getenv("GENESIS_FILE"), "Path to a genesis.json replacing the network's built-in genesis")
+	signerKey := flag.String("signer-key", os.Getenv("SIGNER_KEY"), "Private key this node seals blocks with on proof-of-authority networks")
+	adminAddr := flag.String("admin-addr", api.DefaultAdminAddr, "Loopback address serving the admin routes, such as signer votes")
+	peers := flag.String("peers", os.Getenv("PEERS"), "Comma separated API URLs of the nodes to push new blocks to, such as the other signers")
+	minRelayFee := flag.Float64("min-relay-fee", blockchain.DefaultMinRelayFeePerByte, "Minimum fee in DYP per byte for transactions entering the mempool")
+	dustThreshold := flag.Float64("dust-threshold", blockchain.DefaultDustThreshold, "Smallest DYP output accepted into the mempool")
+	mempoolMaxMB := flag.Int("mempool-max-mb", mempool.DefaultMaxBytes/(1024*1024), "Size cap of the mempool in megabytes")
+	mempoolExpiry := flag.Duration("mempool-expiry", mempool.DefaultExpiry, "How long a transaction may wait in the mempool before it is dropped")
+	flag.Parse()
+
+	network := selectNetwork(*networkName, *genesisFile)
+
 	var bc *blockchain.Blockchain
 
-	if _, err := os.Stat("blockchain.db"); os.IsNotExist(err) {
-		bc = blockchain.CreateBlockchain(genesisAddr)
+	if !blockchain.DBExists() {
+		bc = blockchain.CreateBlockchain(network.Genesis)
 	} else {
 		bc = blockchain.NewBlockchain()
 	}
 
+	policy := blockchain.DefaultMempoolPolicy
+	policy.MinRelayFeePerByte = float32(*minRelayFee)
+	policy.DustThreshold = float32(*dustThreshold)
+	bc.SetMempoolPolicy(policy)
+
+	pool := mempool.NewWithConfig(bc, mempool.Config{
+		MaxBytes: *mempoolMaxMB * 1024 * 1024,
+		Expiry:   *mempoolExpiry,
+	})
+	persistMempool(pool)
+
+	miningServer := NewMiningServer(bc)
+	if *peers != "" {
+		bc.Subscribe(newBlockRelay(bc, *peers))
+	}
+
+	if engine, ok := bc.Engine().(*blockchain.CliqueEngine); ok {
+		// Proof-of-authority nodes with a signing key produce blocks themselves
+		if *signerKey != "" {
+			wallet, err := blockchain.NewWalletFromPrivateKey(*signerKey)
+			if err != nil {
+				log.Fatalf("invalid signer key: %v", err)
+			}
+			engine.Authorize(wallet.PrivateKey)
+			bc.Subscribe(miningServer)
+			go miningServer.produceBlocks(engine)
+		} else {
+			log.Printf("No signer key configured, following the chain without sealing blocks")
+		}
+	}
+
 	go func() {
-		server, err := api.NewServer("8080", bc)
+		server, err := api.NewServer("8080", bc, pool)
 		if err != nil {
 			log.Fatalf("failed to create server: %v", err)
 		}
+		go server.StartAdmin(*adminAddr, miningServer.generateBlocks)
 		server.Start()
 	}()
 
@@ -46,10 +104,74 @@ func main() {
 	}
 
 	s := grpc.NewServer()
-	pb.RegisterMiningServiceServer(s, NewMiningServer(bc))
+	pb.RegisterMiningServiceServer(s, miningServer)
 
 	log.Printf("Mining server listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+// dataFile returns the path of a per-network data file next to the chain database
+func dataFile(name string) string {
+	return filepath.Join(blockchain.DataDir(), name+"-"+blockchain.ActiveNetwork().Name+".dat")
+}
+
+// persistMempool reloads the pending transactions and fee estimates saved in
+// the data directory and saves them again periodically, dropping expired
+// transactions first, and when the node is shut down
+func persistMempool(pool *mempool.Mempool) {
+	path := dataFile("mempool")
+	estimatesPath := dataFile("fee_estimates")
+
+	if err := pool.Estimator().Load(estimatesPath); err != nil {
+		log.Printf("Failed to load fee estimates: %v", err)
+	}
+
+	loaded, dropped, err := pool.Load(path)
+	if err != nil {
+		log.Printf("Failed to load mempool: %v", err)
+	} else if loaded+dropped > 0 {
+		log.Printf("Loaded %d pending transactions from %s, dropped %d that are no longer valid", loaded, path, dropped)
+	}
+
+	save := func() {
+		if err := pool.Save(path); err != nil {
+			log.Printf("Failed to save mempool: %v", err)
+		}
+		if err := pool.Estimator().Save(estimatesPath); err != nil {
+			log.Printf("Failed to save fee estimates: %v", err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(mempoolSaveInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			pool.Expire()
+			save()
+		}
+	}()
+
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		log.Printf("Shutting down, saving %d pending transactions", pool.Count())
+		save()
+		os.Exit(0)
+	}()
+}
+
+// selectNetwork activates the named network, optionally with a custom genesis file
+func selectNetwork(name, genesisFile string) *blockchain.Network {
+	network, err := blockchain.LoadNetwork(name, genesisFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	blockchain.SetNetwork(network)
+	log.Printf("Running on %s (chain ID %d)", network.Name, network.Genesis.ChainID)
+
+	return network
+}
@@ -0,0 +1,259 @@
+package mempool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+)
+
+// Fee estimator parameters
+const (
+	// MaxConfirmTarget is the largest number of blocks fees are estimated for
+	MaxConfirmTarget = 48
+
+	// DefaultConfirmTarget is the confirmation target used when none is given
+	DefaultConfirmTarget = 6
+
+	minBucketFeeRate = 1e-7 // DYP per byte
+	maxBucketFeeRate = 1.0
+	bucketSpacing    = 1.1
+
+	// statsDecay is applied to the history every block, so older blocks
+	// matter less. It halves the weight of a block in about 350 blocks.
+	statsDecay = 0.998
+
+	// successThreshold is the share of transactions in a fee range that must
+	// have confirmed within the target for the range to be recommended
+	successThreshold = 0.85
+
+	// sufficientTxs is the decayed number of transactions a fee range needs
+	// before its success rate is trusted
+	sufficientTxs = 2.0
+)
+
+// FeeEstimator tracks the fee rates of confirmed transactions against the
+// number of blocks they waited in the mempool. Transactions that left the
+// mempool without confirming, and those still waiting past a target, count
+// as failures so low fee rates are not judged only by the few that made it.
+// Fee rates are grouped in exponentially spaced buckets.
+type FeeEstimator struct {
+	mu          sync.RWMutex
+	buckets     []float64   // Upper fee rate of each bucket
+	txCount     []float64   // Decayed number of transactions seen per bucket
+	feeSum      []float64   // Decayed sum of their fee rates
+	confirmed   [][]float64 // confirmed[t][b] counts bucket b transactions confirmed within t+1 blocks
+	unconfirmed [][]float64 // unconfirmed[t][b] counts bucket b transactions still pending after t+1 blocks
+}
+
+// NewFeeEstimator creates an estimator without history
+func NewFeeEstimator() *FeeEstimator {
+	var buckets []float64
+	for rate := minBucketFeeRate; rate < maxBucketFeeRate; rate *= bucketSpacing {
+		buckets = append(buckets, rate)
+	}
+	buckets = append(buckets, math.Inf(1))
+
+	e := &FeeEstimator{
+		buckets:     buckets,
+		txCount:     make([]float64, len(buckets)),
+		feeSum:      make([]float64, len(buckets)),
+		confirmed:   make([][]float64, MaxConfirmTarget),
+		unconfirmed: make([][]float64, MaxConfirmTarget),
+	}
+	for t := range e.confirmed {
+		e.confirmed[t] = make([]float64, len(buckets))
+		e.unconfirmed[t] = make([]float64, len(buckets))
+	}
+
+	return e
+}
+
+// bucketIndex returns the bucket holding feeRate
+func (e *FeeEstimator) bucketIndex(feeRate float64) int {
+	for i, upper := range e.buckets {
+		if feeRate <= upper {
+			return i
+		}
+	}
+	return len(e.buckets) - 1
+}
+
+// confirmation is a transaction of a connected block that was in the mempool,
+// or one still in the mempool after the block
+type confirmation struct {
+	feeRate float64
+	blocks  int // Blocks the transaction waited, 1 if it made the next block
+}
+
+// processBlock decays the history and records the transactions a block
+// confirmed. pending holds the transactions left in the mempool with the
+// blocks they have waited so far; they replace the previous block's pending
+// set, since each of them is counted once it confirms or fails.
+func (e *FeeEstimator) processBlock(confirmations, pending []confirmation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for b := range e.buckets {
+		e.txCount[b] *= statsDecay
+		e.feeSum[b] *= statsDecay
+		for t := range e.confirmed {
+			e.confirmed[t][b] *= statsDecay
+		}
+	}
+
+	for _, c := range confirmations {
+		b := e.bucketIndex(c.feeRate)
+		e.txCount[b]++
+		e.feeSum[b] += c.feeRate
+
+		// Transactions that waited longer than every target only count
+		// against the estimates
+		for t := max(c.blocks, 1) - 1; t < MaxConfirmTarget; t++ {
+			e.confirmed[t][b]++
+		}
+	}
+
+	for t := range e.unconfirmed {
+		clear(e.unconfirmed[t])
+	}
+	for _, p := range pending {
+		b := e.bucketIndex(p.feeRate)
+		for t := 0; t < min(p.blocks, MaxConfirmTarget); t++ {
+			e.unconfirmed[t][b]++
+		}
+	}
+}
+
+// processFailures records transactions that left the mempool without
+// confirming, evicted or expired. They count against every target.
+func (e *FeeEstimator) processFailures(feeRates []float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, feeRate := range feeRates {
+		b := e.bucketIndex(feeRate)
+		e.txCount[b]++
+		e.feeSum[b] += feeRate
+	}
+}
+
+// Estimate returns the lowest fee rate, in DYP per byte, at which transactions
+// have reliably confirmed within target blocks. Buckets are combined from the
+// highest fee rate down until they hold enough transactions, and each group
+// must meet the success threshold, with transactions pending for longer than
+// target counted as failures. It returns false without enough history.
+func (e *FeeEstimator) Estimate(target int) (float32, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	t := min(max(target, 1), MaxConfirmTarget) - 1
+
+	best := -1.0
+	var seen, total, confirmed, feeSum float64
+	for b := len(e.buckets) - 1; b >= 0; b-- {
+		seen += e.txCount[b]
+		total += e.txCount[b] + e.unconfirmed[t][b]
+		confirmed += e.confirmed[t][b]
+		feeSum += e.feeSum[b]
+		if total < sufficientTxs {
+			continue
+		}
+
+		if confirmed/total < successThreshold {
+			break
+		}
+		best = feeSum / seen
+		seen, total, confirmed, feeSum = 0, 0, 0, 0
+	}
+
+	if best < 0 {
+		return 0, false
+	}
+	return float32(best), true
+}
+
+// persistedEstimates is the on-disk form of the estimator history
+type persistedEstimates struct {
+	Buckets   []float64
+	TxCount   []float64
+	FeeSum    []float64
+	Confirmed [][]float64
+}
+
+// Save writes the estimator history to path. The pending transactions are
+// not saved, the next block counts them again.
+func (e *FeeEstimator) Save(path string) error {
+	e.mu.RLock()
+	snapshot := persistedEstimates{
+		Buckets:   e.buckets,
+		TxCount:   e.txCount,
+		FeeSum:    e.feeSum,
+		Confirmed: e.confirmed,
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(snapshot)
+	e.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode fee estimates: %v", err)
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// Load restores history written by Save. A missing file is not an error, and
+// history recorded with other buckets is discarded.
+func (e *FeeEstimator) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read fee estimates: %v", err)
+	}
+
+	var snapshot persistedEstimates
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode fee estimates: %v", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(snapshot.Buckets) != len(e.buckets) || len(snapshot.Confirmed) != len(e.confirmed) ||
+		len(snapshot.TxCount) != len(e.buckets) || len(snapshot.FeeSum) != len(e.buckets) {
+		return fmt.Errorf("fee estimates were saved with a different bucket layout")
+	}
+	for t := range snapshot.Confirmed {
+		if len(snapshot.Confirmed[t]) != len(e.buckets) {
+			return fmt.Errorf("fee estimates were saved with a different bucket layout")
+		}
+	}
+
+	e.txCount = snapshot.TxCount
+	e.feeSum = snapshot.FeeSum
+	e.confirmed = snapshot.Confirmed
+	return nil
+}
+
+// Estimator returns the fee estimator fed by the blocks the mempool sees
+func (mp *Mempool) Estimator() *FeeEstimator {
+	return mp.estimator
+}
+
+// EstimateFee returns the fee rate, in DYP per byte, recommended to confirm
+// within target blocks. It is never below the current mempool minimum, which
+// is also the estimate without enough history. The second result reports
+// whether the estimate comes from history.
+func (mp *Mempool) EstimateFee(target int) (float32, bool) {
+	minFee := mp.MinFeePerByte()
+
+	rate, ok := mp.estimator.Estimate(target)
+	if !ok || rate < minFee {
+		return minFee, ok
+	}
+	return rate, true
+}
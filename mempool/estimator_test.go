@@ -0,0 +1,82 @@
+package mempool
+
+import (
+	"math"
+	"testing"
+)
+
+// txsAt returns n transactions paying feeRate that waited blocks
+func txsAt(feeRate float64, blocks, n int) []confirmation {
+	txs := make([]confirmation, n)
+	for i := range txs {
+		txs[i] = confirmation{feeRate: feeRate, blocks: blocks}
+	}
+	return txs
+}
+
+func TestFeeEstimates(t *testing.T) {
+	const high, low = 1e-4, 1e-5
+
+	// Ten transactions at the high fee rate made the next block, ten at the
+	// low fee rate waited ten blocks
+	history := func(e *FeeEstimator) {
+		e.processBlock(append(txsAt(high, 1, 10), txsAt(low, 10, 10)...), nil)
+	}
+
+	tests := []struct {
+		name   string
+		record func(e *FeeEstimator)
+		target int
+		want   float32
+		wantOK bool
+	}{
+		{"no history", func(e *FeeEstimator) {}, 1, 0, false},
+		{"short target needs the high fee rate", history, 1, high, true},
+		{"long target accepts the low fee rate", history, 10, low, true},
+		{
+			name: "evicted and expired transactions count as failures",
+			record: func(e *FeeEstimator) {
+				history(e)
+				e.processFailures([]float64{low, low, low, low, low, low, low, low, low, low})
+			},
+			target: 10, want: high, wantOK: true,
+		},
+		{
+			name: "transactions pending past the target count as failures",
+			record: func(e *FeeEstimator) {
+				history(e)
+				e.processBlock(nil, txsAt(low, 12, 10))
+			},
+			target: 10, want: high, wantOK: true,
+		},
+		{
+			name: "transactions pending less than the target do not count",
+			record: func(e *FeeEstimator) {
+				history(e)
+				e.processBlock(nil, txsAt(low, 12, 10))
+			},
+			target: 20, want: low, wantOK: true,
+		},
+		{
+			name: "pending transactions are only counted until the next block",
+			record: func(e *FeeEstimator) {
+				history(e)
+				e.processBlock(nil, txsAt(low, 12, 10))
+				e.processBlock(nil, nil)
+			},
+			target: 10, want: low, wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewFeeEstimator()
+			tt.record(e)
+
+			got, ok := e.Estimate(tt.target)
+			if ok != tt.wantOK || math.Abs(float64(got-tt.want)) > 1e-6*float64(tt.want) {
+				t.Fatalf("Estimate(%d) = %g, %t, want %g, %t", tt.target, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package mempool
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"dyp_chain/blockchain"
+)
+
+// Default mempool limits
+const (
+	// DefaultMaxBytes is the default cap on the serialized size of all
+	// pending transactions
+	DefaultMaxBytes = 300 * 1024 * 1024
+
+	// DefaultExpiry is how long a transaction may wait for a block by default
+	DefaultExpiry = 14 * 24 * time.Hour
+
+	// minFeeHalfLife is how quickly the dynamic minimum fee falls back to the
+	// relay fee once the pool stops filling up
+	minFeeHalfLife = 12 * time.Hour
+)
+
+// Config holds the mempool size and age limits
+type Config struct {
+	MaxBytes int           // Cap on the total serialized size of pending transactions
+	Expiry   time.Duration // Pending transactions older than this are dropped
+}
+
+// DefaultConfig is the configuration New uses
+var DefaultConfig = Config{
+	MaxBytes: DefaultMaxBytes,
+	Expiry:   DefaultExpiry,
+}
+
+// Info summarizes the state of the mempool
+type Info struct {
+	Size          int     // Number of pending transactions
+	Bytes         int     // Total serialized size of pending transactions
+	MaxBytes      int     // Configured size cap
+	MinFeePerByte float32 // Lowest fee rate currently accepted
+	Expiry        time.Duration
+}
+
+// Info returns the pool size, byte usage and current minimum fee rate
+func (mp *Mempool) Info() Info {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return Info{
+		Size:          len(mp.pool),
+		Bytes:         mp.bytes,
+		MaxBytes:      mp.cfg.MaxBytes,
+		MinFeePerByte: mp.minFeeLocked(time.Now()),
+		Expiry:        mp.cfg.Expiry,
+	}
+}
+
+// MinFeePerByte returns the lowest fee rate the mempool currently accepts.
+// It is the relay fee of the node policy, raised while the pool is full.
+func (mp *Mempool) MinFeePerByte() float32 {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return mp.minFeeLocked(time.Now())
+}
+
+// minFeeLocked decays the rolling minimum fee and returns the effective
+// minimum fee rate. It must be called with mp.mu held.
+func (mp *Mempool) minFeeLocked(now time.Time) float32 {
+	relayFee := mp.chain.MempoolPolicy().MinRelayFeePerByte
+
+	if mp.rollingMinFee > 0 {
+		// Fall faster the emptier the pool is
+		halfLife := minFeeHalfLife
+		if mp.bytes < mp.cfg.MaxBytes/4 {
+			halfLife /= 4
+		} else if mp.bytes < mp.cfg.MaxBytes/2 {
+			halfLife /= 2
+		}
+
+		elapsed := now.Sub(mp.minFeeUpdated)
+		mp.rollingMinFee *= math.Pow(0.5, elapsed.Seconds()/halfLife.Seconds())
+		mp.minFeeUpdated = now
+
+		if mp.rollingMinFee < float64(relayFee)/2 {
+			mp.rollingMinFee = 0
+		}
+	}
+
+	return float32(math.Max(mp.rollingMinFee, float64(relayFee)))
+}
+
+// checkMinFee refuses transactions paying less than the dynamic minimum fee.
+// It must be called with mp.mu held.
+func (mp *Mempool) checkMinFee(desc *TxDesc) error {
+	if minFee := mp.minFeeLocked(time.Now()); desc.FeePerByte() < minFee {
+		return &blockchain.PolicyError{
+			Reason: "mempool-min-fee",
+			Detail: fmt.Sprintf("fee rate %f DYP/byte is below the mempool minimum of %f DYP/byte", desc.FeePerByte(), minFee),
+		}
+	}
+	return nil
+}
+
+// trimLocked evicts the transactions with the lowest fee rate, counted
+// together with their descendants, until the pool fits in its size cap. It
+// raises the minimum fee above the best evicted rate so they are not simply
+// resubmitted. It must be called with mp.mu held.
+func (mp *Mempool) trimLocked() []*TxDesc {
+	var evicted []*TxDesc
+	increment := float64(mp.chain.MempoolPolicy().MinRelayFeePerByte)
+
+	for mp.bytes > mp.cfg.MaxBytes {
+		var worst *TxDesc
+		worstRate := math.Inf(1)
+		for _, desc := range mp.pool {
+			if rate := mp.packageFeePerByte(desc); rate < worstRate {
+				worst, worstRate = desc, rate
+			}
+		}
+
+		evicted = append(evicted, mp.removeWithDescendantsLocked(worst)...)
+
+		if rate := worstRate + increment; rate > mp.rollingMinFee {
+			mp.rollingMinFee = rate
+			mp.minFeeUpdated = time.Now()
+		}
+	}
+
+	if len(evicted) > 0 {
+		log.Printf("[Mempool] Evicted %d transactions to stay under %d bytes, minimum fee is now %f DYP/byte",
+			len(evicted), mp.cfg.MaxBytes, mp.rollingMinFee)
+	}
+
+	return evicted
+}
+
+// Expire drops the transactions that have waited longer than the configured
+// expiry and returns how many were dropped. The fee estimator counts them as
+// failures.
+func (mp *Mempool) Expire() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	cutoff := time.Now().Add(-mp.cfg.Expiry)
+	var expired []*TxDesc
+	for _, desc := range mp.pool {
+		// Descendants of an expired transaction go with it. Entries removed
+		// during the loop are not visited.
+		if desc.Added.Before(cutoff) {
+			expired = append(expired, mp.removeWithDescendantsLocked(desc)...)
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("[Mempool] Expired %d transactions older than %s", len(expired), mp.cfg.Expiry)
+		mp.estimator.processFailures(feeRates(expired))
+	}
+
+	return len(expired)
+}
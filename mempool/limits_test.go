@@ -0,0 +1,69 @@
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"dyp_chain/blockchain"
+)
+
+func TestEvictionUnderSizeCap(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       float32 // Output of the incoming transaction, spending a 10 DYP coin
+		wantErr     string
+		wantEvicted int // Index of the pending transaction evicted, -1 for none
+	}{
+		{"higher fee rate evicts the lowest", 9.5, "", 0},
+		{"lowest fee rate is refused", 9.9, "mempool-full", -1},
+		{"fee below the relay fee is refused", 9.999, "min-relay-fee", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, _ := newTestPool(t, DefaultConfig)
+			coins := fund(t, bc, 4, 10)
+
+			// The cap holds three pending transactions but not a fourth
+			size := spend(t, bc, coins[3:], tt.value).Size()
+			mp := NewWithConfig(bc, Config{MaxBytes: 3*size + size/2, Expiry: DefaultExpiry})
+
+			var pending []*blockchain.Transaction
+			for i, value := range []float32{9.8, 9.7, 9.6} {
+				tx := spend(t, bc, coins[i:i+1], value)
+				mustAdd(t, mp, tx)
+				pending = append(pending, tx)
+			}
+
+			incoming := spend(t, bc, coins[3:], tt.value)
+			err := mp.Add(incoming)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("transaction refused: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+
+			for i, tx := range pending {
+				if _, ok := mp.Get(tx.ID); ok == (i == tt.wantEvicted) {
+					t.Fatalf("pending transaction %d kept is %t, want %t", i, ok, i != tt.wantEvicted)
+				}
+			}
+			if _, ok := mp.Get(incoming.ID); ok != (err == nil) {
+				t.Fatalf("incoming transaction kept is %t, want %t", ok, err == nil)
+			}
+
+			// An eviction raises the minimum fee above the evicted fee rate,
+			// so the evicted transaction cannot simply come back
+			if tt.wantEvicted >= 0 {
+				evicted := pending[tt.wantEvicted]
+				if rate := evicted.Fee / float32(evicted.Size()); mp.MinFeePerByte() <= rate {
+					t.Fatalf("minimum fee %f DYP/byte is not above the evicted %f DYP/byte", mp.MinFeePerByte(), rate)
+				}
+				if err := mp.Add(evicted); err == nil {
+					t.Fatal("evicted transaction was admitted again")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"dyp_chain/blockchain"
+)
+
+var (
+	// ErrAlreadyKnown is returned when a transaction is already in the pool
+	ErrAlreadyKnown = errors.New("transaction is already in the mempool")
+
+	// ErrConflict is returned when a transaction spends an output that a
+	// pending transaction already spends and does not qualify to replace it
+	ErrConflict = errors.New("transaction conflicts with a pending transaction")
+)
+
+// Outpoint identifies a transaction output by transaction ID and index
+type Outpoint struct {
+	TxID string
+	Vout int
+}
+
+// TxDesc is a pending transaction together with its admission data
+type TxDesc struct {
+	Tx     *blockchain.Transaction
+	Added  time.Time
+	Height int // Chain height when the transaction was admitted
+	Size   int
+	Fee    float32
+}
+
+// FeePerByte returns the fee rate the transaction pays
+func (d *TxDesc) FeePerByte() float32 {
+	return d.Fee / float32(d.Size)
+}
+
+// Mempool holds validated transactions waiting for a block. It is safe for
+// concurrent use and indexes transactions by ID and by the outputs they spend.
+type Mempool struct {
+	mu        sync.RWMutex
+	chain     *blockchain.Blockchain
+	cfg       Config
+	pool      map[string]*TxDesc
+	outpoints map[Outpoint]*TxDesc
+	bytes     int // Total size of the pending transactions
+
+	estimator *FeeEstimator
+
+	// rollingMinFee is the fee rate, in DYP per byte, raised when the pool
+	// evicts transactions to stay under its size cap. It decays over time.
+	rollingMinFee float64
+	minFeeUpdated time.Time
+
+	// generation changes whenever transactions leave the pool or a block is
+	// connected, which can invalidate checks made without the lock
+	generation uint64
+}
+
+// New creates a mempool for chain with the default limits and attaches it, so
+// the chain's AddTransaction goes through the mempool and connected blocks
+// evict confirmed and conflicting transactions
+func New(chain *blockchain.Blockchain) *Mempool {
+	return NewWithConfig(chain, DefaultConfig)
+}
+
+// NewWithConfig creates and attaches a mempool with the given limits
+func NewWithConfig(chain *blockchain.Blockchain, cfg Config) *Mempool {
+	mp := &Mempool{
+		chain:     chain,
+		cfg:       cfg,
+		estimator: NewFeeEstimator(),
+		pool:      make(map[string]*TxDesc),
+		outpoints: make(map[Outpoint]*TxDesc),
+	}
+	chain.SetTxPool(mp)
+	chain.Subscribe(mp)
+
+	return mp
+}
+
+// maxAddAttempts bounds how often Add validates a transaction again when the
+// pool or the chain changed while it was being checked against the chain
+const maxAddAttempts = 3
+
+// errPoolChanged is returned by admit when transactions left the pool or a
+// block was connected since the chain checks ran
+var errPoolChanged = errors.New("mempool changed while the transaction was validated")
+
+// Add validates tx against the node policy, the chain and the other pending
+// transactions, then admits it, replacing the pending transactions it
+// conflicts with if it pays enough more. When the pool is over its size cap
+// the transactions paying the lowest fee rate are evicted; if that would
+// evict tx itself, tx is refused and the pool is left as it was.
+func (mp *Mempool) Add(tx *blockchain.Transaction) error {
+	for attempt := 0; attempt < maxAddAttempts; attempt++ {
+		mp.mu.RLock()
+		_, known := mp.pool[hex.EncodeToString(tx.ID)]
+		pending := mp.txsLocked()
+		generation := mp.generation
+		mp.mu.RUnlock()
+		if known {
+			return ErrAlreadyKnown
+		}
+
+		// The chain checks scan the chain, so they run against a snapshot of
+		// the pending transactions without holding the lock
+		height, err := mp.checkChain(tx, pending)
+		if err != nil {
+			return err
+		}
+
+		if err := mp.admit(tx, height, generation); err != errPoolChanged {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w, try again", errPoolChanged)
+}
+
+// admit runs the checks against the other pending transactions and adds tx,
+// unless the pool changed since generation
+func (mp *Mempool) admit(tx *blockchain.Transaction, height int, generation uint64) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.generation != generation {
+		return errPoolChanged
+	}
+
+	desc, replaced, err := mp.validateLocked(tx, height)
+	if err != nil {
+		return err
+	}
+
+	prevMinFee, prevMinFeeUpdated := mp.rollingMinFee, mp.minFeeUpdated
+	mp.replaceLocked(replaced)
+	mp.addLocked(desc)
+
+	evicted := mp.trimLocked()
+	if containsDesc(evicted, desc) {
+		// The pool fit in its cap before, so putting back what was evicted
+		// and replaced restores it exactly
+		for _, e := range evicted {
+			if e != desc {
+				mp.addLocked(e)
+			}
+		}
+		for _, r := range replaced {
+			mp.addLocked(r)
+		}
+		mp.rollingMinFee, mp.minFeeUpdated = prevMinFee, prevMinFeeUpdated
+
+		return &blockchain.PolicyError{
+			Reason: "mempool-full",
+			Detail: fmt.Sprintf("fee rate %f DYP/byte is too low to enter the full mempool", desc.FeePerByte()),
+		}
+	}
+
+	if len(replaced) > 0 {
+		log.Printf("[Mempool] Transaction %x replaces %d pending transactions", desc.Tx.ID, len(replaced))
+	}
+	mp.estimator.processFailures(feeRates(evicted))
+
+	return nil
+}
+
+// checkChain validates tx against the node policy and the chain, letting its
+// inputs spend outputs of the pending transactions, and returns the chain
+// height it was checked at
+func (mp *Mempool) checkChain(tx *blockchain.Transaction, pending []*blockchain.Transaction) (int, error) {
+	if err := mp.chain.CheckPolicy(tx, pending); err != nil {
+		return 0, err
+	}
+
+	height := mp.chain.GetHeight()
+	if err := mp.chain.CheckTransactionLocks(tx, pending, height+1, time.Now().Unix()); err != nil {
+		return 0, err
+	}
+	if err := mp.chain.CheckTransaction(tx, pending); err != nil {
+		return 0, err
+	}
+	if err := mp.chain.CheckUnspent(tx); err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// validateLocked runs the admission checks of tx against the other pending
+// transactions, once checkChain passed at height, and returns its descriptor
+// and the pending transactions it replaces. It must be called with mp.mu held.
+func (mp *Mempool) validateLocked(tx *blockchain.Transaction, height int) (*TxDesc, []*TxDesc, error) {
+	if _, ok := mp.pool[hex.EncodeToString(tx.ID)]; ok {
+		return nil, nil, ErrAlreadyKnown
+	}
+
+	seen := make(map[Outpoint]bool)
+	var conflicts []*TxDesc
+	for _, vin := range tx.Vin {
+		outpoint := Outpoint{hex.EncodeToString(vin.Txid), vin.Vout}
+		if seen[outpoint] {
+			return nil, nil, fmt.Errorf("transaction spends %s:%d twice", outpoint.TxID, outpoint.Vout)
+		}
+		seen[outpoint] = true
+
+		if spender, ok := mp.outpoints[outpoint]; ok && !containsDesc(conflicts, spender) {
+			conflicts = append(conflicts, spender)
+		}
+	}
+
+	if err := mp.checkChainLimits(tx); err != nil {
+		return nil, nil, err
+	}
+
+	desc := &TxDesc{
+		Tx:     tx,
+		Added:  time.Now(),
+		Height: height,
+		Size:   tx.Size(),
+		Fee:    tx.Fee,
+	}
+	if err := mp.checkMinFee(desc); err != nil {
+		return nil, nil, err
+	}
+
+	if len(conflicts) == 0 {
+		return desc, nil, nil
+	}
+	replaced, err := mp.checkReplacement(desc, conflicts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return desc, replaced, nil
+}
+
+// containsDesc reports whether descs holds desc
+func containsDesc(descs []*TxDesc, desc *TxDesc) bool {
+	for _, d := range descs {
+		if d == desc {
+			return true
+		}
+	}
+	return false
+}
+
+// addLocked indexes an admitted transaction. It must be called with mp.mu held.
+func (mp *Mempool) addLocked(desc *TxDesc) {
+	mp.pool[hex.EncodeToString(desc.Tx.ID)] = desc
+	mp.bytes += desc.Size
+	for _, vin := range desc.Tx.Vin {
+		mp.outpoints[Outpoint{hex.EncodeToString(vin.Txid), vin.Vout}] = desc
+	}
+}
+
+// removeLocked drops a transaction and its outpoint index entries. It must be
+// called with mp.mu held.
+func (mp *Mempool) removeLocked(desc *TxDesc) {
+	mp.generation++
+	delete(mp.pool, hex.EncodeToString(desc.Tx.ID))
+	mp.bytes -= desc.Size
+	for _, vin := range desc.Tx.Vin {
+		outpoint := Outpoint{hex.EncodeToString(vin.Txid), vin.Vout}
+		if mp.outpoints[outpoint] == desc {
+			delete(mp.outpoints, outpoint)
+		}
+	}
+}
+
+// Remove drops the transaction with the given ID, if it is pending, together
+// with the pending transactions that spend its outputs
+func (mp *Mempool) Remove(txID []byte) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if desc, ok := mp.pool[hex.EncodeToString(txID)]; ok {
+		mp.removeWithDescendantsLocked(desc)
+	}
+}
+
+// Get returns the pending transaction with the given ID
+func (mp *Mempool) Get(txID []byte) (*blockchain.Transaction, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	desc, ok := mp.pool[hex.EncodeToString(txID)]
+	if !ok {
+		return nil, false
+	}
+	return desc.Tx, true
+}
+
+// Spender returns the pending transaction spending an output, if any
+func (mp *Mempool) Spender(txID []byte, vout int) (*blockchain.Transaction, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	desc, ok := mp.outpoints[Outpoint{hex.EncodeToString(txID), vout}]
+	if !ok {
+		return nil, false
+	}
+	return desc.Tx, true
+}
+
+// Count returns the number of pending transactions
+func (mp *Mempool) Count() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return len(mp.pool)
+}
+
+// Descs returns the pending transactions with their admission data, oldest
+// first. A transaction always comes after the pending transactions it spends.
+func (mp *Mempool) Descs() []*TxDesc {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	descs := make([]*TxDesc, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		descs = append(descs, desc)
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Added.Before(descs[j].Added)
+	})
+
+	return descs
+}
+
+// Transactions returns the pending transactions, oldest first
+func (mp *Mempool) Transactions() []*blockchain.Transaction {
+	descs := mp.Descs()
+	txs := make([]*blockchain.Transaction, len(descs))
+	for i, desc := range descs {
+		txs[i] = desc.Tx
+	}
+	return txs
+}
+
+// BlockConnected evicts the transactions confirmed by block and any pending
+// transaction that spends an output the block spent, with its descendants.
+// Transactions spending outputs of the confirmed ones stay pending. The fee
+// estimator learns how long the confirmed transactions waited, and how long
+// the others have been waiting.
+func (mp *Mempool) BlockConnected(block *blockchain.Block) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.generation++
+	var confirmations []confirmation
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		if desc, ok := mp.pool[hex.EncodeToString(tx.ID)]; ok {
+			confirmations = append(confirmations, confirmation{
+				feeRate: float64(desc.FeePerByte()),
+				blocks:  block.Height - desc.Height,
+			})
+			mp.removeLocked(desc)
+		}
+		for _, vin := range tx.Vin {
+			if conflict, ok := mp.outpoints[Outpoint{hex.EncodeToString(vin.Txid), vin.Vout}]; ok {
+				mp.removeWithDescendantsLocked(conflict)
+			}
+		}
+	}
+
+	pending := make([]confirmation, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		pending = append(pending, confirmation{
+			feeRate: float64(desc.FeePerByte()),
+			blocks:  block.Height - desc.Height,
+		})
+	}
+
+	mp.estimator.processBlock(confirmations, pending)
+}
+
+// feeRates returns the fee rates of descs, as the fee estimator records them
+func feeRates(descs []*TxDesc) []float64 {
+	rates := make([]float64, len(descs))
+	for i, desc := range descs {
+		rates[i] = float64(desc.FeePerByte())
+	}
+	return rates
+}
@@ -0,0 +1,122 @@
+package mempool
+
+import (
+	"testing"
+
+	"dyp_chain/blockchain"
+)
+
+// The regtest genesis funds the address of devKey
+const (
+	devKey     = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	devAddress = "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"
+	miner      = "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"
+)
+
+// coin is an output of devAddress
+type coin struct {
+	txID  []byte
+	vout  int
+	value float32
+}
+
+// newTestPool creates a regtest chain in a temporary directory and attaches
+// a mempool limited by cfg
+func newTestPool(t *testing.T, cfg Config) (*blockchain.Blockchain, *Mempool) {
+	t.Helper()
+
+	prevNetwork, prevDir := blockchain.ActiveNetwork(), blockchain.DataDir()
+	blockchain.SetNetwork(blockchain.RegTest)
+	blockchain.SetDataDir(t.TempDir())
+
+	bc := blockchain.CreateBlockchain(blockchain.RegTest.Genesis)
+	t.Cleanup(func() {
+		bc.DB.Close()
+		blockchain.SetDataDir(prevDir)
+		blockchain.SetNetwork(prevNetwork)
+	})
+
+	return bc, NewWithConfig(bc, cfg)
+}
+
+// spend signs a transaction spending coins into outputs of the given values
+// back to devAddress. What the outputs leave of the coins is the fee.
+func spend(t *testing.T, bc *blockchain.Blockchain, coins []coin, values ...float32) *blockchain.Transaction {
+	t.Helper()
+
+	wallet, err := blockchain.NewWalletFromPrivateKey(devKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &blockchain.Transaction{From: devAddress, To: devAddress, Amount: values[0]}
+	for _, c := range coins {
+		tx.Vin = append(tx.Vin, blockchain.TXInput{Txid: c.txID, Vout: c.vout, PubKey: wallet.PublicKey})
+		tx.Fee += c.value
+	}
+	for _, value := range values {
+		tx.Vout = append(tx.Vout, *blockchain.NewTXOutput(value, devAddress))
+		tx.Fee -= value
+	}
+	tx.ID = tx.Hash()
+	bc.SignTransaction(tx, wallet.PrivateKey)
+
+	return tx
+}
+
+// coinsOf returns the outputs of tx
+func coinsOf(tx *blockchain.Transaction) []coin {
+	coins := make([]coin, len(tx.Vout))
+	for i, out := range tx.Vout {
+		coins[i] = coin{txID: tx.ID, vout: i, value: out.Value}
+	}
+	return coins
+}
+
+// fund mines a block splitting the genesis allocation into n confirmed coins
+// of value and returns them
+func fund(t *testing.T, bc *blockchain.Blockchain, n int, value float32) []coin {
+	t.Helper()
+
+	var genesis []coin
+	var total float32
+	for _, utxo := range bc.FindPendingUTXOs(devAddress) {
+		genesis = append(genesis, coin{txID: utxo.TxID, vout: utxo.Vout, value: utxo.Output.Value})
+		total += utxo.Output.Value
+	}
+
+	values := make([]float32, n, n+1)
+	for i := range values {
+		values[i] = value
+	}
+	tx := spend(t, bc, genesis, append(values, total-float32(n)*value)...)
+	mine(t, bc, tx)
+
+	return coinsOf(tx)[:n]
+}
+
+// mine mines a block holding txs, whose coinbase claims their fees
+func mine(t *testing.T, bc *blockchain.Blockchain, txs ...*blockchain.Transaction) {
+	t.Helper()
+
+	height := bc.GetHeight() + 1
+	fees := float32(0)
+	for _, tx := range txs {
+		fees += tx.Fee
+	}
+	coinbase := blockchain.NewCoinbaseTx(miner, "test", height, bc.Engine().BlockReward(height)+fees)
+	if _, err := bc.MineBlock(append(txs, coinbase)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mustAdd adds txs to the pool and fails the test if one is refused
+func mustAdd(t *testing.T, mp *Mempool, txs ...*blockchain.Transaction) {
+	t.Helper()
+
+	for _, tx := range txs {
+		if err := mp.Add(tx); err != nil {
+			t.Fatalf("transaction %x refused: %v", tx.ID, err)
+		}
+	}
+}
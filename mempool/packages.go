@@ -0,0 +1,123 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"dyp_chain/blockchain"
+)
+
+// Limits on chains of unconfirmed transactions. Both counts include the
+// transaction itself.
+const (
+	maxAncestors   = 25
+	maxDescendants = 25
+)
+
+// txsLocked returns the pending transactions in no particular order. It must
+// be called with mp.mu held.
+func (mp *Mempool) txsLocked() []*blockchain.Transaction {
+	txs := make([]*blockchain.Transaction, 0, len(mp.pool))
+	for _, desc := range mp.pool {
+		txs = append(txs, desc.Tx)
+	}
+	return txs
+}
+
+// ancestorsLocked returns the pending transactions whose outputs tx spends,
+// directly or through other pending transactions. It must be called with
+// mp.mu held.
+func (mp *Mempool) ancestorsLocked(tx *blockchain.Transaction) []*TxDesc {
+	var ancestors []*TxDesc
+	seen := make(map[*TxDesc]bool)
+
+	queue := []*blockchain.Transaction{tx}
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+
+		for _, vin := range child.Vin {
+			parent, ok := mp.pool[hex.EncodeToString(vin.Txid)]
+			if !ok || seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			ancestors = append(ancestors, parent)
+			queue = append(queue, parent.Tx)
+		}
+	}
+
+	return ancestors
+}
+
+// descendantsLocked returns the pending transactions that spend outputs of
+// desc, directly or through other pending transactions. It must be called
+// with mp.mu held.
+func (mp *Mempool) descendantsLocked(desc *TxDesc) []*TxDesc {
+	var descendants []*TxDesc
+	seen := map[*TxDesc]bool{desc: true}
+
+	queue := []*TxDesc{desc}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		parentID := hex.EncodeToString(parent.Tx.ID)
+		for vout := range parent.Tx.Vout {
+			child, ok := mp.outpoints[Outpoint{parentID, vout}]
+			if !ok || seen[child] {
+				continue
+			}
+			seen[child] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+	}
+
+	return descendants
+}
+
+// checkChainLimits refuses a transaction that would make a chain of
+// unconfirmed transactions too long. It must be called with mp.mu held.
+func (mp *Mempool) checkChainLimits(tx *blockchain.Transaction) error {
+	ancestors := mp.ancestorsLocked(tx)
+	if len(ancestors)+1 > maxAncestors {
+		return &blockchain.PolicyError{
+			Reason: "too-long-mempool-chain",
+			Detail: fmt.Sprintf("transaction has %d unconfirmed ancestors, the maximum is %d", len(ancestors), maxAncestors-1),
+		}
+	}
+
+	for _, ancestor := range ancestors {
+		if descendants := mp.descendantsLocked(ancestor); len(descendants)+2 > maxDescendants {
+			return &blockchain.PolicyError{
+				Reason: "too-long-mempool-chain",
+				Detail: fmt.Sprintf("pending transaction %x already has %d unconfirmed descendants, the maximum is %d",
+					ancestor.Tx.ID, len(descendants), maxDescendants-1),
+			}
+		}
+	}
+
+	return nil
+}
+
+// packageFeePerByte returns the fee rate of desc together with its
+// descendants, which leave the pool with it. It must be called with mp.mu held.
+func (mp *Mempool) packageFeePerByte(desc *TxDesc) float64 {
+	fee, size := float64(desc.Fee), desc.Size
+	for _, descendant := range mp.descendantsLocked(desc) {
+		fee += float64(descendant.Fee)
+		size += descendant.Size
+	}
+	return fee / float64(size)
+}
+
+// removeWithDescendantsLocked drops desc and every pending transaction that
+// depends on it, and returns what was dropped. It must be called with mp.mu held.
+func (mp *Mempool) removeWithDescendantsLocked(desc *TxDesc) []*TxDesc {
+	removed := append([]*TxDesc{desc}, mp.descendantsLocked(desc)...)
+	for _, r := range removed {
+		mp.removeLocked(r)
+	}
+	return removed
+}
@@ -0,0 +1,67 @@
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"dyp_chain/blockchain"
+)
+
+func TestChainLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		// next builds the next transaction of the chain given the coin it
+		// starts from and the transactions already admitted
+		next    func(t *testing.T, bc *blockchain.Blockchain, c coin, added []*blockchain.Transaction) *blockchain.Transaction
+		wantErr string
+	}{
+		{
+			name: "ancestors",
+			next: func(t *testing.T, bc *blockchain.Blockchain, c coin, added []*blockchain.Transaction) *blockchain.Transaction {
+				if len(added) == 0 {
+					return spend(t, bc, []coin{c}, 9.9)
+				}
+				last := added[len(added)-1]
+				return spend(t, bc, coinsOf(last), last.Vout[0].Value-0.1)
+			},
+			wantErr: "has 25 unconfirmed ancestors, the maximum is 24",
+		},
+		{
+			name: "descendants",
+			next: func(t *testing.T, bc *blockchain.Blockchain, c coin, added []*blockchain.Transaction) *blockchain.Transaction {
+				if len(added) == 0 {
+					values := make([]float32, maxDescendants)
+					for i := range values {
+						values[i] = 0.35
+					}
+					return spend(t, bc, []coin{c}, values...)
+				}
+				return spend(t, bc, coinsOf(added[0])[len(added)-1:len(added)], 0.25)
+			},
+			wantErr: "already has 24 unconfirmed descendants, the maximum is 24",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, mp := newTestPool(t, DefaultConfig)
+			coins := fund(t, bc, 1, 10)
+
+			// 25 transactions fit in one chain, the 26th does not
+			var added []*blockchain.Transaction
+			for len(added) < maxAncestors {
+				tx := tt.next(t, bc, coins[0], added)
+				mustAdd(t, mp, tx)
+				added = append(added, tx)
+			}
+
+			err := mp.Add(tt.next(t, bc, coins[0], added))
+			if err == nil || !strings.Contains(err.Error(), "too-long-mempool-chain") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want too-long-mempool-chain with %q", err, tt.wantErr)
+			}
+			if mp.Count() != len(added) {
+				t.Fatalf("pool holds %d transactions, want %d", mp.Count(), len(added))
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package mempool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dyp_chain/blockchain"
+)
+
+// persistVersion is the version of the mempool file format
+const persistVersion = 1
+
+// persistedMempool is the on-disk form of the mempool
+type persistedMempool struct {
+	Version int
+	Entries []persistedTx
+}
+
+// persistedTx is a pending transaction and the time and chain height at
+// which it entered the mempool. Files written before Height was saved decode
+// with a zero Height.
+type persistedTx struct {
+	Tx     *blockchain.Transaction
+	Added  time.Time
+	Height int
+}
+
+// Save writes the pending transactions to path
+func (mp *Mempool) Save(path string) error {
+	snapshot := persistedMempool{Version: persistVersion}
+	for _, desc := range mp.Descs() {
+		snapshot.Entries = append(snapshot.Entries, persistedTx{Tx: desc.Tx, Added: desc.Added, Height: desc.Height})
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to encode mempool: %v", err)
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// writeFileAtomic replaces the file at path with data, so a crash while
+// saving never leaves a truncated file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save %s: %v", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save %s: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save %s: %v", path, err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads transactions saved by Save and admits those that are still valid
+// against the current chain and have not expired, keeping their original
+// admission time and height, so the fee estimator counts the blocks they
+// waited before the restart. A missing file is not an error. It returns how
+// many entries were loaded and dropped.
+func (mp *Mempool) Load(path string) (loaded, dropped int, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read mempool: %v", err)
+	}
+
+	var snapshot persistedMempool
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return 0, 0, fmt.Errorf("failed to decode mempool: %v", err)
+	}
+	if snapshot.Version != persistVersion {
+		return 0, 0, fmt.Errorf("unsupported mempool file version %d", snapshot.Version)
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	cutoff := time.Now().Add(-mp.cfg.Expiry)
+	for _, entry := range snapshot.Entries {
+		if entry.Added.Before(cutoff) {
+			dropped++
+			continue
+		}
+
+		// The node is starting up, so holding the lock through the chain
+		// checks keeps nobody waiting
+		height, err := mp.checkChain(entry.Tx, mp.txsLocked())
+		if err != nil {
+			log.Printf("[Mempool] Dropping saved transaction %x: %v", entry.Tx.ID, err)
+			dropped++
+			continue
+		}
+		desc, replaced, err := mp.validateLocked(entry.Tx, height)
+		if err != nil {
+			log.Printf("[Mempool] Dropping saved transaction %x: %v", entry.Tx.ID, err)
+			dropped++
+			continue
+		}
+
+		desc.Added = entry.Added
+		if entry.Height > 0 && entry.Height <= desc.Height {
+			desc.Height = entry.Height
+		}
+		mp.replaceLocked(replaced)
+		mp.addLocked(desc)
+		loaded++
+	}
+
+	evicted := mp.trimLocked()
+	mp.estimator.processFailures(feeRates(evicted))
+	return loaded - len(evicted), dropped + len(evicted), nil
+}
@@ -0,0 +1,64 @@
+package mempool
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		expiry      time.Duration // Expiry of the mempool loading the file
+		wantLoaded  int
+		wantDropped int
+	}{
+		{"restores pending transactions", DefaultExpiry, 2, 0},
+		{"drops expired transactions", time.Nanosecond, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, mp := newTestPool(t, DefaultConfig)
+			coins := fund(t, bc, 1, 10)
+
+			parent := spend(t, bc, coins, 9.9)
+			mustAdd(t, mp, parent)
+			mustAdd(t, mp, spend(t, bc, coinsOf(parent), 9.8))
+			saved := mp.Descs()
+
+			// The transactions keep the height they were admitted at
+			mine(t, bc)
+
+			path := filepath.Join(t.TempDir(), "mempool.dat")
+			if err := mp.Save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			restored := NewWithConfig(bc, Config{MaxBytes: DefaultMaxBytes, Expiry: tt.expiry})
+			loaded, dropped, err := restored.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded != tt.wantLoaded || dropped != tt.wantDropped {
+				t.Fatalf("loaded %d and dropped %d, want %d and %d", loaded, dropped, tt.wantLoaded, tt.wantDropped)
+			}
+
+			for _, want := range saved[:tt.wantLoaded] {
+				var got *TxDesc
+				for _, desc := range restored.Descs() {
+					if string(desc.Tx.ID) == string(want.Tx.ID) {
+						got = desc
+					}
+				}
+				if got == nil {
+					t.Fatalf("transaction %x was not restored", want.Tx.ID)
+				}
+				if !got.Added.Equal(want.Added) || got.Height != want.Height {
+					t.Fatalf("transaction %x restored as added %v at height %d, want %v at height %d",
+						want.Tx.ID, got.Added, got.Height, want.Added, want.Height)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package mempool
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// maxReplaced bounds how many pending transactions one replacement may evict
+const maxReplaced = 100
+
+// checkReplacement decides whether desc may replace the pending transactions
+// it conflicts with. A replacement must pay a higher fee rate than each
+// transaction it conflicts with and a higher absolute fee than everything it
+// evicts, including their descendants, plus the relay fee for its own size.
+// It returns the transactions to evict. It must be called with mp.mu held.
+func (mp *Mempool) checkReplacement(desc *TxDesc, conflicts []*TxDesc) ([]*TxDesc, error) {
+	replaced := make(map[string]*TxDesc)
+	for _, conflict := range conflicts {
+		if desc.FeePerByte() <= conflict.FeePerByte() {
+			return nil, fmt.Errorf("%w: replacement fee rate %f DYP/byte must be higher than %f DYP/byte of transaction %x",
+				ErrConflict, desc.FeePerByte(), conflict.FeePerByte(), conflict.Tx.ID)
+		}
+
+		replaced[hex.EncodeToString(conflict.Tx.ID)] = conflict
+		for _, descendant := range mp.descendantsLocked(conflict) {
+			replaced[hex.EncodeToString(descendant.Tx.ID)] = descendant
+		}
+	}
+
+	if len(replaced) > maxReplaced {
+		return nil, fmt.Errorf("%w: replacement would evict %d transactions, the maximum is %d",
+			ErrConflict, len(replaced), maxReplaced)
+	}
+
+	for _, vin := range desc.Tx.Vin {
+		if _, ok := replaced[hex.EncodeToString(vin.Txid)]; ok {
+			return nil, fmt.Errorf("%w: replacement spends an output of transaction %x it replaces",
+				ErrConflict, vin.Txid)
+		}
+	}
+
+	var replacedFees float64
+	evicted := make([]*TxDesc, 0, len(replaced))
+	for _, r := range replaced {
+		replacedFees += float64(r.Fee)
+		evicted = append(evicted, r)
+	}
+
+	if float64(desc.Fee) <= replacedFees {
+		return nil, fmt.Errorf("%w: replacement fee %f DYP must be higher than the %f DYP paid by the transactions it replaces",
+			ErrConflict, desc.Fee, replacedFees)
+	}
+
+	relayFee := mp.chain.MempoolPolicy().MinFee(desc.Size)
+	if extra := float64(desc.Fee) - replacedFees; extra < float64(relayFee) {
+		return nil, fmt.Errorf("%w: replacement must add at least %f DYP in fees for its %d bytes, it adds %f DYP",
+			ErrConflict, relayFee, desc.Size, extra)
+	}
+
+	return evicted, nil
+}
+
+// replaceLocked evicts the transactions a replacement replaces. It must be
+// called with mp.mu held.
+func (mp *Mempool) replaceLocked(replaced []*TxDesc) {
+	for _, r := range replaced {
+		mp.removeLocked(r)
+	}
+}
@@ -0,0 +1,66 @@
+package mempool
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"dyp_chain/blockchain"
+)
+
+func TestReplaceByFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int       // Pending transactions, each spending one 10 DYP coin into three outputs and paying 0.1 DYP
+		withChild bool      // The first of them has a child paying another 0.1 DYP
+		values    []float32 // Outputs of the replacement, which spends every coin
+		wantErr   string
+	}{
+		{"higher fee rate and fee replaces", 1, false, []float32{9.7}, ""},
+		{"lower fee rate is refused", 1, false, []float32{2.5, 2.5, 2.5, 2.4}, "fee rate"},
+		{"higher fee rate without a higher fee is refused", 1, false, []float32{9.905}, "must be higher than the 0.100000 DYP"},
+		{"fee increase below the relay fee is refused", 1, false, []float32{9.895}, "must add at least"},
+		{"replaces descendants too", 1, true, []float32{9.6}, ""},
+		{"fee must cover the descendants", 1, true, []float32{9.85}, "must be higher than the 0.200000 DYP"},
+		{"evicts up to the cap", maxReplaced, false, []float32{980}, ""},
+		{"evicting more than the cap is refused", maxReplaced + 1, false, []float32{990}, "the maximum is 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, mp := newTestPool(t, DefaultConfig)
+			coins := fund(t, bc, tt.conflicts, 10)
+
+			var pending []*blockchain.Transaction
+			for i := range coins {
+				tx := spend(t, bc, coins[i:i+1], 4, 3, 2.9)
+				mustAdd(t, mp, tx)
+				pending = append(pending, tx)
+			}
+			if tt.withChild {
+				child := spend(t, bc, coinsOf(pending[0])[:1], 3.9)
+				mustAdd(t, mp, child)
+				pending = append(pending, child)
+			}
+
+			replacement := spend(t, bc, coins, tt.values...)
+			err := mp.Add(replacement)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("replacement refused: %v", err)
+			}
+			if tt.wantErr != "" && (!errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want %v containing %q", err, ErrConflict, tt.wantErr)
+			}
+
+			replaced := err == nil
+			for _, tx := range pending {
+				if _, ok := mp.Get(tx.ID); ok == replaced {
+					t.Fatalf("pending transaction %x kept is %t, want %t", tx.ID, ok, !replaced)
+				}
+			}
+			if _, ok := mp.Get(replacement.ID); ok != replaced {
+				t.Fatalf("replacement kept is %t, want %t", ok, replaced)
+			}
+		})
+	}
+}
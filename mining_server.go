@@ -5,31 +5,28 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
-	"sort"
 	"sync"
 	"time"
 
 	blockchain "dyp_chain/blockchain"
 	pb "dyp_chain/proto"
 
-	"github.com/boltdb/bolt"
 	"github.com/ethereum/go-ethereum/common"
 )
 
-const blocksBucket = "blocks"
-
 type miningServer struct {
 	pb.UnimplementedMiningServiceServer
-	blockchain  *blockchain.Blockchain
-	mu          sync.Mutex
-	lastAdjTime time.Time
+	blockchain *blockchain.Blockchain
+	mu         sync.Mutex
+
+	sealMu   sync.Mutex
+	sealStop chan struct{} // Closed when the tip moves while the producer seals
 }
 
 // NewMiningServer creates a new mining server instance
 func NewMiningServer(bc *blockchain.Blockchain) *miningServer {
 	return &miningServer{
-		blockchain:  bc,
-		lastAdjTime: time.Now(),
+		blockchain: bc,
 	}
 }
 
@@ -41,118 +38,39 @@ func calculateBlockSize(block *blockchain.Block) int {
 
 	// Add transaction sizes
 	for _, tx := range block.Transactions {
-		// Add fixed transaction fields
-		size += len(tx.ID) + len(tx.From) + len(tx.To) + 8 + 8 + len(tx.Signature)
-
-		// Add inputs
-		for _, in := range tx.Vin {
-			size += len(in.Txid) + 4 + len(in.Signature) + len(in.PubKey)
-		}
-
-		// Add outputs
-		for _, out := range tx.Vout {
-			size += 4 + len(out.Address)
-		}
+		size += tx.Size()
 	}
 
 	return size
 }
 
-// GetBlockTemplate prepares a new block template for mining
-func (s *miningServer) GetBlockTemplate(ctx context.Context, req *pb.BlockTemplateRequest) (*pb.BlockTemplateResponse, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !common.IsHexAddress(req.MinerAddress) {
-		return nil, fmt.Errorf("invalid miner Ethereum address format")
-	}
-
-	log.Printf("[Server] Getting block template for miner: %s", req.MinerAddress)
-
-	// Get transactions from mempool
+// newBlock assembles an unsealed block paying the reward to minerAddress from
+// the mempool transaction packages with the highest fee per byte that fit in a
+// block. It also returns the fees collected by the block.
+func (s *miningServer) newBlock(minerAddress, coinbaseData string) (*blockchain.Block, float32, error) {
+	// Get transactions from mempool that may go into the next block
+	height := s.blockchain.GetHeight() + 1
 	pendingTxs := s.blockchain.GetPendingTransactions()
 	log.Printf("[Server] Found %d total transactions in mempool", len(pendingTxs))
-
-	// Create a slice of transactions with their sizes and fees for sorting
-	type txWithMetadata struct {
-		tx   *blockchain.Transaction
-		size int
-		fee  float32
-	}
-
-	var txsMetadata []txWithMetadata
-
-	// Calculate size and fee for each transaction
-	for _, tx := range pendingTxs {
-		if !tx.IsCoinbase() {
-			// Calculate transaction size
-			size := len(tx.ID) + len(tx.From) + len(tx.To) + 8 + 8 + len(tx.Signature)
-			for _, in := range tx.Vin {
-				size += len(in.Txid) + 4 + len(in.Signature) + len(in.PubKey)
-			}
-			for _, out := range tx.Vout {
-				size += 4 + len(out.Address)
-			}
-
-			txsMetadata = append(txsMetadata, txWithMetadata{
-				tx:   tx,
-				size: size,
-				fee:  tx.Fee,
-			})
-		}
-	}
-
-	// Sort transactions by fee per byte (descending) to maximize reward/size ratio
-	sort.Slice(txsMetadata, func(i, j int) bool {
-		feePerByteI := float64(txsMetadata[i].fee) / float64(txsMetadata[i].size)
-		feePerByteJ := float64(txsMetadata[j].fee) / float64(txsMetadata[j].size)
-		return feePerByteI > feePerByteJ
-	})
-
-	// Select transactions while respecting block size limit
-	var selectedTxs []*blockchain.Transaction
-	totalSize := 0
-	totalFees := float32(0)
-	realTxCount := 0
+	pendingTxs = finalTransactions(s.blockchain, pendingTxs, height, time.Now().Unix())
 
 	// Reserve space for block header and coinbase transaction
 	headerSize := 8 + 32 + 32 + 4 + 4 // timestamp + prevBlockHash + hash + nonce + height
 	coinbaseSize := 100               // Approximate size for coinbase transaction
 	remainingSize := blockchain.MaxBlockSize - headerSize - coinbaseSize
 
-	// Select transactions that fit in the block
-	for _, txMeta := range txsMetadata {
-		if totalSize+txMeta.size <= remainingSize {
-			selectedTxs = append(selectedTxs, txMeta.tx)
-			totalSize += txMeta.size
-			totalFees += txMeta.tx.Fee
-			realTxCount++
-			log.Printf("[Server] Selected transaction: From=%s, To=%s, Amount=%f, Fee=%f, Size=%d",
-				txMeta.tx.From, txMeta.tx.To, txMeta.tx.Amount, txMeta.tx.Fee, txMeta.size)
-		}
+	// Select transaction packages by combined fee rate, parents first
+	selectedTxs, totalFees := selectPackages(pendingTxs, (*blockchain.Transaction).Size, remainingSize)
+	for _, tx := range selectedTxs {
+		log.Printf("[Server] Selected transaction: From=%s, To=%s, Amount=%f, Fee=%f, Size=%d",
+			tx.From, tx.To, tx.Amount, tx.Fee, tx.Size())
 	}
 
-	log.Printf("[Server] Selected %d transactions with total fees: %f", realTxCount, totalFees)
-
-	// Check if this is genesis block
-	var isGenesis bool
-	err := s.blockchain.DB.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(blocksBucket))
-		if b == nil {
-			isGenesis = true
-			return nil
-		}
-		c := b.Cursor()
-		k, _ := c.First()
-		isGenesis = k == nil
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to check blockchain state: %v", err)
-	}
+	log.Printf("[Server] Selected %d transactions with total fees: %f", len(selectedTxs), totalFees)
 
 	// Add mining reward transaction
-	reward := blockchain.NewCoinbaseTx(req.MinerAddress, "Mining reward", isGenesis, totalFees)
+	blockReward := s.blockchain.Engine().BlockReward(height) + totalFees
+	reward := blockchain.NewCoinbaseTx(minerAddress, coinbaseData, height, blockReward)
 	selectedTxs = append(selectedTxs, reward)
 
 	// Create a block template
@@ -162,94 +80,74 @@ func (s *miningServer) GetBlockTemplate(ctx context.Context, req *pb.BlockTempla
 	blockSize := calculateBlockSize(block)
 	if blockSize > blockchain.MaxBlockSize {
 		log.Printf("[Server] Block size %d exceeds maximum %d bytes", blockSize, blockchain.MaxBlockSize)
-		return nil, fmt.Errorf("block size exceeds maximum allowed size")
-	}
-
-	// Adjust difficulty if needed
-	lastBlock := s.blockchain.GetLastBlock()
-	if lastBlock != nil && !isGenesis {
-		if block.Height%blockchain.DifficultyAdjustmentInterval == 0 {
-			actualTimespan := time.Since(s.lastAdjTime)
-			newDifficulty := blockchain.CalculateNextDifficulty(blockchain.GetTargetBits(), actualTimespan)
-			blockchain.SetDifficulty(newDifficulty)
-			s.lastAdjTime = time.Now()
-			log.Printf("[Server] Adjusted difficulty to %d", newDifficulty)
-		}
+		return nil, 0, fmt.Errorf("block size exceeds maximum allowed size")
 	}
 
 	log.Printf("[Server] Prepared block template: Height=%d, PrevHash=%x, Size=%d bytes",
 		block.Height, block.PrevBlockHash, blockSize)
 
+	return block, totalFees, nil
+}
+
+// GetBlockTemplate prepares a new block template for mining
+func (s *miningServer) GetBlockTemplate(ctx context.Context, req *pb.BlockTemplateRequest) (*pb.BlockTemplateResponse, error) {
+	if s.producesBlocks() {
+		return nil, errExternalMining
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !common.IsHexAddress(req.MinerAddress) {
+		return nil, fmt.Errorf("invalid miner Ethereum address format")
+	}
+
+	log.Printf("[Server] Getting block template for miner: %s", req.MinerAddress)
+
+	// Commit to the miner's extra data in the coinbase if given
+	coinbaseData := "Mining reward"
+	if req.CoinbaseData != "" {
+		if len(req.CoinbaseData) > blockchain.MaxCoinbaseExtraData {
+			return nil, fmt.Errorf("coinbase data exceeds %d bytes", blockchain.MaxCoinbaseExtraData)
+		}
+		coinbaseData = req.CoinbaseData
+	}
+	block, totalFees, err := s.newBlock(req.MinerAddress, coinbaseData)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert block to protobuf format
 	pbBlock := &pb.Block{
 		Timestamp:     block.Timestamp,
 		PrevBlockHash: block.PrevBlockHash,
 		Height:        int32(block.Height),
 		Transactions:  make([]*pb.Transaction, len(block.Transactions)),
+		Version:       block.Version,
+		Bits:          int32(block.Bits),
 	}
 
 	// Convert transactions
 	for i, tx := range block.Transactions {
-		pbTx := &pb.Transaction{
-			From:          tx.From,
-			To:            tx.To,
-			Amount:        tx.Amount,
-			Fee:           tx.Fee,
-			TransactionId: tx.ID,
-			Signature:     tx.Signature,
-			Vin:           make([]*pb.TXInput, len(tx.Vin)),
-			Vout:          make([]*pb.TXOutput, len(tx.Vout)),
-		}
-
 		if tx.IsCoinbase() {
-			if isGenesis {
-				log.Printf("[Server] Converting genesis coinbase transaction for miner: %s, Amount=%f DYP (50 DYP + %f fees)",
-					tx.To, tx.Amount, totalFees)
-			} else {
-				log.Printf("[Server] Converting coinbase transaction for miner: %s, Amount=%f DYP (50 DYP + %f fees)",
-					tx.To, tx.Amount, totalFees)
-			}
-			pbTx.Vin = []*pb.TXInput{
-				{
-					Txid:      []byte{},
-					Vout:      -1,
-					Signature: tx.Signature,
-					PubKey:    []byte("Mining reward"),
-				},
-			}
-			pbTx.Vout = []*pb.TXOutput{
-				{
-					Value:   tx.Amount,
-					Address: tx.To,
-				},
-			}
-		} else {
-			for j, vin := range tx.Vin {
-				pbTx.Vin[j] = &pb.TXInput{
-					Txid:      vin.Txid,
-					Vout:      int32(vin.Vout),
-					Signature: vin.Signature,
-					PubKey:    vin.PubKey,
-				}
-			}
-			for j, vout := range tx.Vout {
-				pbTx.Vout[j] = &pb.TXOutput{
-					Value:   vout.Value,
-					Address: vout.Address,
-				}
-			}
+			log.Printf("[Server] Converting coinbase transaction for miner: %s, Amount=%f DYP (%f fees)",
+				tx.To, tx.Amount, totalFees)
 		}
-		pbBlock.Transactions[i] = pbTx
+		pbBlock.Transactions[i] = pb.TransactionToProto(tx)
 	}
 
 	return &pb.BlockTemplateResponse{
 		Block:      pbBlock,
-		Difficulty: int32(blockchain.GetTargetBits()),
+		Difficulty: int32(block.Bits),
 	}, nil
 }
 
 // SubmitBlock handles the submission of a mined block
 func (s *miningServer) SubmitBlock(ctx context.Context, req *pb.SubmitBlockRequest) (*pb.SubmitBlockResponse, error) {
+	if s.producesBlocks() {
+		return nil, errExternalMining
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -261,34 +159,7 @@ func (s *miningServer) SubmitBlock(ctx context.Context, req *pb.SubmitBlockReque
 	totalFees := float32(0)
 
 	for i, tx := range req.Block.Transactions {
-		transactions[i] = &blockchain.Transaction{
-			ID:        tx.TransactionId,
-			From:      tx.From,
-			To:        tx.To,
-			Amount:    tx.Amount,
-			Fee:       tx.Fee,
-			Signature: tx.Signature,
-			Vin:       make([]blockchain.TXInput, len(tx.Vin)),
-			Vout:      make([]blockchain.TXOutput, len(tx.Vout)),
-		}
-
-		// Convert inputs
-		for j, vin := range tx.Vin {
-			transactions[i].Vin[j] = blockchain.TXInput{
-				Txid:      vin.Txid,
-				Vout:      int(vin.Vout),
-				Signature: vin.Signature,
-				PubKey:    vin.PubKey,
-			}
-		}
-
-		// Convert outputs
-		for j, vout := range tx.Vout {
-			transactions[i].Vout[j] = blockchain.TXOutput{
-				Value:   vout.Value,
-				Address: vout.Address,
-			}
-		}
+		transactions[i] = pb.TransactionFromProto(tx)
 
 		if tx.From == "coinbase" {
 			log.Printf("[Server] Processing coinbase transaction for miner: %s, Amount=%f", tx.To, tx.Amount)
@@ -302,26 +173,21 @@ func (s *miningServer) SubmitBlock(ctx context.Context, req *pb.SubmitBlockReque
 
 	// Create the block without mining it
 	block := &blockchain.Block{
-		Timestamp:     req.Block.Timestamp,
-		Transactions:  transactions,
-		PrevBlockHash: req.Block.PrevBlockHash,
-		Hash:          req.BlockHash,
-		Nonce:         int(req.Nonce),
-		Height:        int(req.Block.Height),
-	}
-
-	// Verify the proof of work
-	pow := blockchain.NewProofOfWork(block)
-	if !pow.Validate() {
-		log.Printf("[Server] Block validation failed: invalid proof of work. Hash=%x, Nonce=%d", req.BlockHash, req.Nonce)
-		return &pb.SubmitBlockResponse{
-			Success:      false,
-			ErrorMessage: "invalid proof of work",
-		}, nil
-	}
-	log.Printf("[Server] Block proof of work validation successful")
-
-	// Add the block to the blockchain
+		BlockHeader: blockchain.BlockHeader{
+			Version:       req.Block.Version,
+			PrevBlockHash: req.Block.PrevBlockHash,
+			Timestamp:     req.Block.Timestamp,
+			Bits:          int(req.Block.Bits),
+			Nonce:         req.Nonce,
+			Height:        int(req.Block.Height),
+		},
+		Hash:         req.BlockHash,
+		Transactions: transactions,
+	}
+	block.MerkleRoot = block.HashTransactions()
+
+	// Add the block to the blockchain; the consensus engine verifies the
+	// header's difficulty and seal
 	err := s.blockchain.AddBlock(block, transactions)
 	if err != nil {
 		log.Printf("[Server] Failed to add block to chain: %v", err)
@@ -349,7 +215,7 @@ func (s *miningServer) GetBlockchainStatus(ctx context.Context, req *pb.Blockcha
 	return &pb.BlockchainStatusResponse{
 		Height:          int32(s.blockchain.GetHeight()),
 		LatestBlockHash: hex.EncodeToString(tip.Hash),
-		Difficulty:      int32(blockchain.GetTargetBits()),
+		Difficulty:      int32(s.blockchain.NextDifficulty()),
 	}, nil
 }
 
@@ -362,15 +228,7 @@ func (s *miningServer) GetPendingTransactions(ctx context.Context, req *pb.Pendi
 	pbTxs := make([]*pb.Transaction, len(pendingTxs))
 
 	for i, tx := range pendingTxs {
-		pbTx := &pb.Transaction{
-			From:          tx.From,
-			To:            tx.To,
-			Amount:        tx.Amount,
-			Fee:           tx.Fee,
-			TransactionId: tx.ID,
-			Signature:     tx.Signature,
-		}
-		pbTxs[i] = pbTx
+		pbTxs[i] = pb.TransactionToProto(tx)
 	}
 
 	return &pb.PendingTransactionsResponse{
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/hex"
+	"log"
+
+	blockchain "dyp_chain/blockchain"
+)
+
+// txEntry is a mempool transaction considered for a block, linked to the
+// other pending transactions it spends and that spend it
+type txEntry struct {
+	tx       *blockchain.Transaction
+	size     int
+	parents  []*txEntry
+	children []*txEntry
+	included bool
+	version  int // Bumped whenever an ancestor is included, invalidating queued scores
+}
+
+// ancestry returns the transactions that must be included for e, its
+// not yet included ancestors followed by e, parents before children
+func (e *txEntry) ancestry() []*txEntry {
+	var pkg []*txEntry
+	seen := make(map[*txEntry]bool)
+
+	var visit func(*txEntry)
+	visit = func(entry *txEntry) {
+		if entry.included || seen[entry] {
+			return
+		}
+		seen[entry] = true
+		for _, parent := range entry.parents {
+			visit(parent)
+		}
+		pkg = append(pkg, entry)
+	}
+	visit(e)
+
+	return pkg
+}
+
+// packageScore returns the combined size and fees of a package
+func packageScore(pkg []*txEntry) (int, float32) {
+	size, fee := 0, float32(0)
+	for _, entry := range pkg {
+		size += entry.size
+		fee += entry.tx.Fee
+	}
+	return size, fee
+}
+
+// queuedPackage is a transaction waiting in the selection queue, scored by the
+// fee rate of its package when it was queued
+type queuedPackage struct {
+	entry   *txEntry
+	rate    float64
+	version int
+}
+
+// packageQueue is a max-heap of packages by fee rate
+type packageQueue []queuedPackage
+
+func (q packageQueue) Len() int            { return len(q) }
+func (q packageQueue) Less(i, j int) bool  { return q[i].rate > q[j].rate }
+func (q packageQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *packageQueue) Push(x interface{}) { *q = append(*q, x.(queuedPackage)) }
+func (q *packageQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// push queues entry scored by the fee rate of its current package
+func (q *packageQueue) push(entry *txEntry) {
+	size, fee := packageScore(entry.ancestry())
+	heap.Push(q, queuedPackage{entry: entry, rate: float64(fee) / float64(size), version: entry.version})
+}
+
+// selectPackages picks mempool transactions for a block of at most maxSize
+// bytes. Each transaction is scored together with its unconfirmed ancestors,
+// so a high fee child pays for its parent, and the best package that fits is
+// taken first. The result lists parents before the transactions spending them.
+func selectPackages(txs []*blockchain.Transaction, sizeOf func(*blockchain.Transaction) int, maxSize int) ([]*blockchain.Transaction, float32) {
+	entries := make(map[string]*txEntry, len(txs))
+	for _, tx := range txs {
+		if !tx.IsCoinbase() {
+			entries[hex.EncodeToString(tx.ID)] = &txEntry{tx: tx, size: sizeOf(tx)}
+		}
+	}
+	for _, entry := range entries {
+		linked := make(map[*txEntry]bool)
+		for _, vin := range entry.tx.Vin {
+			parent, ok := entries[hex.EncodeToString(vin.Txid)]
+			if !ok || linked[parent] {
+				continue
+			}
+			linked[parent] = true
+			entry.parents = append(entry.parents, parent)
+			parent.children = append(parent.children, entry)
+		}
+	}
+
+	queue := &packageQueue{}
+	for _, entry := range entries {
+		queue.push(entry)
+	}
+
+	var selected []*blockchain.Transaction
+	totalSize, totalFees := 0, float32(0)
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(queuedPackage)
+		if item.entry.included || item.version != item.entry.version {
+			continue // Already in the block, or queued again with a newer score
+		}
+
+		pkg := item.entry.ancestry()
+		size, fee := packageScore(pkg)
+		if totalSize+size > maxSize {
+			continue
+		}
+
+		for _, entry := range pkg {
+			entry.included = true
+			selected = append(selected, entry.tx)
+		}
+		totalSize += size
+		totalFees += fee
+
+		// Packages of the descendants no longer include these transactions
+		rescored := make(map[*txEntry]bool)
+		var rescore func(*txEntry)
+		rescore = func(entry *txEntry) {
+			for _, child := range entry.children {
+				if child.included || rescored[child] {
+					continue
+				}
+				rescored[child] = true
+				child.version++
+				queue.push(child)
+				rescore(child)
+			}
+		}
+		for _, entry := range pkg {
+			rescore(entry)
+		}
+	}
+
+	return selected, totalFees
+}
+
+// finalTransactions drops the mempool transactions whose absolute or relative
+// lock times keep them out of a block at height and blockTime, together with
+// every transaction spending their outputs
+func finalTransactions(bc *blockchain.Blockchain, txs []*blockchain.Transaction, height int, blockTime int64) []*blockchain.Transaction {
+	excluded := make(map[string]bool)
+	for _, tx := range txs {
+		if err := bc.CheckTransactionLocks(tx, txs, height, blockTime); err != nil {
+			log.Printf("[Server] Skipping transaction %x: %v", tx.ID, err)
+			excluded[hex.EncodeToString(tx.ID)] = true
+		}
+	}
+
+	// Descendants of a skipped transaction would spend outputs missing from
+	// the block
+	for changed := len(excluded) > 0; changed; {
+		changed = false
+		for _, tx := range txs {
+			id := hex.EncodeToString(tx.ID)
+			if excluded[id] {
+				continue
+			}
+			for _, vin := range tx.Vin {
+				if excluded[hex.EncodeToString(vin.Txid)] {
+					excluded[id] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	final := make([]*blockchain.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if !excluded[hex.EncodeToString(tx.ID)] {
+			final = append(final, tx)
+		}
+	}
+	return final
+}
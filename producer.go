@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	blockchain "dyp_chain/blockchain"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// errExternalMining is returned to gRPC miners on proof-of-authority networks,
+// where the node's own block producer seals every block
+var errExternalMining = errors.New("external mining is disabled on proof-of-authority networks")
+
+// producesBlocks reports whether the node seals its own blocks instead of
+// serving templates to external miners
+func (s *miningServer) producesBlocks() bool {
+	_, ok := s.blockchain.Engine().(*blockchain.CliqueEngine)
+	return ok
+}
+
+// produceBlocks seals a block with the node's signing key whenever it is the
+// signer's turn. Mempool transactions are picked the same way as for block
+// templates and the reward is paid to the signer.
+func (s *miningServer) produceBlocks(engine *blockchain.CliqueEngine) {
+	signer := engine.Signer().Hex()
+	log.Printf("[Producer] Sealing blocks as %s every %s", signer, engine.Period())
+
+	for {
+		stop := s.interruptOnNewTip()
+		s.mu.Lock()
+		block, totalFees, err := s.newBlock(signer, "Sealed by "+signer)
+		s.mu.Unlock()
+		if err != nil {
+			log.Printf("[Producer] Failed to assemble block: %v", err)
+			time.Sleep(engine.Period())
+			continue
+		}
+
+		// Sealing waits until the block is due, so the lock is not held. A
+		// block of another signer arriving meanwhile takes the height.
+		err = engine.Seal(s.blockchain, block, stop)
+		if errors.Is(err, blockchain.ErrSealStopped) {
+			continue
+		}
+		if errors.Is(err, blockchain.ErrRecentlySigned) || errors.Is(err, blockchain.ErrUnauthorizedSigner) {
+			// Another signer has to seal the next block
+			time.Sleep(time.Second)
+			continue
+		}
+		if err != nil {
+			log.Printf("[Producer] Failed to seal block: %v", err)
+			time.Sleep(engine.Period())
+			continue
+		}
+
+		s.mu.Lock()
+		err = s.blockchain.AddBlock(block, block.Transactions)
+		s.mu.Unlock()
+		if err != nil {
+			log.Printf("[Producer] Sealed block rejected: %v", err)
+			continue
+		}
+
+		log.Printf("[Producer] Sealed block: Height=%d, Hash=%x, Transactions=%d, Fees=%f",
+			block.Height, block.Hash, len(block.Transactions)-1, totalFees)
+	}
+}
+
+// interruptOnNewTip returns a channel that is closed once the next block is
+// connected to the chain
+func (s *miningServer) interruptOnNewTip() <-chan struct{} {
+	s.sealMu.Lock()
+	defer s.sealMu.Unlock()
+
+	s.sealStop = make(chan struct{})
+	return s.sealStop
+}
+
+// BlockConnected stops sealing on top of a tip that is no longer the tip
+func (s *miningServer) BlockConnected(block *blockchain.Block) {
+	s.sealMu.Lock()
+	defer s.sealMu.Unlock()
+
+	if s.sealStop != nil {
+		close(s.sealStop)
+		s.sealStop = nil
+	}
+}
+
+// generateBlocks instantly mines count blocks paying the rewards to address,
+// on networks with on-demand mining such as regtest. Each block is assembled
+// like a block template, so mempool transactions go in by fee rate while they
+// fit and their lock times allow.
+func (s *miningServer) generateBlocks(count int, address string) ([]*blockchain.Block, error) {
+	if !blockchain.ActiveNetwork().OnDemandMining {
+		return nil, fmt.Errorf("block generation is not available on %s", blockchain.ActiveNetwork().Name)
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("block count must be greater than 0")
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("[Server] Generating %d blocks for %s", count, address)
+
+	blocks := make([]*blockchain.Block, 0, count)
+	for i := 0; i < count; i++ {
+		block, _, err := s.newBlock(address, "Generated block")
+		if err != nil {
+			return blocks, err
+		}
+		if err := s.blockchain.Engine().Seal(s.blockchain, block, nil); err != nil {
+			return blocks, err
+		}
+		if err := s.blockchain.AddBlock(block, block.Transactions); err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
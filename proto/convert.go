@@ -0,0 +1,129 @@
+package proto
+
+import (
+	"dyp_chain/blockchain"
+)
+
+// TransactionToProto converts a blockchain transaction into its protobuf form
+func TransactionToProto(tx *blockchain.Transaction) *Transaction {
+	pbTx := &Transaction{
+		From:          tx.From,
+		To:            tx.To,
+		Amount:        tx.Amount,
+		Fee:           tx.Fee,
+		TransactionId: tx.ID,
+		Signature:     tx.Signature,
+		Vin:           make([]*TXInput, len(tx.Vin)),
+		Vout:          make([]*TXOutput, len(tx.Vout)),
+		ExtraNonce:    tx.ExtraNonce,
+		LockTime:      tx.LockTime,
+	}
+	if tx.Issuance != nil {
+		pbTx.Issuance = &TokenIssuance{
+			Name:     tx.Issuance.Name,
+			Symbol:   tx.Issuance.Symbol,
+			Supply:   tx.Issuance.Supply,
+			Metadata: tx.Issuance.Metadata,
+		}
+	}
+
+	for i, vin := range tx.Vin {
+		pbTx.Vin[i] = &TXInput{
+			Txid:       vin.Txid,
+			Vout:       int32(vin.Vout),
+			Signature:  vin.Signature,
+			PubKey:     vin.PubKey,
+			Sequence:   vin.Sequence,
+			Signatures: vin.Signatures,
+			Preimage:   vin.Preimage,
+		}
+	}
+
+	for i, vout := range tx.Vout {
+		pbTx.Vout[i] = &TXOutput{
+			Value:       vout.Value,
+			Address:     vout.Address,
+			Data:        vout.Data,
+			TokenId:     vout.TokenID,
+			TokenAmount: vout.TokenAmount,
+		}
+		if vout.Multisig != nil {
+			pbTx.Vout[i].MultisigThreshold = int32(vout.Multisig.Threshold)
+			pbTx.Vout[i].MultisigAddresses = vout.Multisig.Addresses
+		}
+		if vout.HTLC != nil {
+			pbTx.Vout[i].Htlc = &HTLCLock{
+				HashType:  vout.HTLC.HashType,
+				Hash:      vout.HTLC.Hash,
+				Recipient: vout.HTLC.Recipient,
+				Sender:    vout.HTLC.Sender,
+				Timeout:   int32(vout.HTLC.Timeout),
+			}
+		}
+	}
+
+	return pbTx
+}
+
+// TransactionFromProto converts a protobuf transaction back into a blockchain transaction
+func TransactionFromProto(pbTx *Transaction) *blockchain.Transaction {
+	tx := &blockchain.Transaction{
+		ID:         pbTx.TransactionId,
+		From:       pbTx.From,
+		To:         pbTx.To,
+		Amount:     pbTx.Amount,
+		Fee:        pbTx.Fee,
+		Signature:  pbTx.Signature,
+		Vin:        make([]blockchain.TXInput, len(pbTx.Vin)),
+		Vout:       make([]blockchain.TXOutput, len(pbTx.Vout)),
+		ExtraNonce: pbTx.ExtraNonce,
+		LockTime:   pbTx.LockTime,
+	}
+	if pbTx.Issuance != nil {
+		tx.Issuance = &blockchain.TokenIssuance{
+			Name:     pbTx.Issuance.Name,
+			Symbol:   pbTx.Issuance.Symbol,
+			Supply:   pbTx.Issuance.Supply,
+			Metadata: pbTx.Issuance.Metadata,
+		}
+	}
+
+	for i, vin := range pbTx.Vin {
+		tx.Vin[i] = blockchain.TXInput{
+			Txid:       vin.Txid,
+			Vout:       int(vin.Vout),
+			Signature:  vin.Signature,
+			PubKey:     vin.PubKey,
+			Sequence:   vin.Sequence,
+			Signatures: vin.Signatures,
+			Preimage:   vin.Preimage,
+		}
+	}
+
+	for i, vout := range pbTx.Vout {
+		tx.Vout[i] = blockchain.TXOutput{
+			Value:       vout.Value,
+			Address:     vout.Address,
+			Data:        vout.Data,
+			TokenID:     vout.TokenId,
+			TokenAmount: vout.TokenAmount,
+		}
+		if vout.MultisigThreshold != 0 {
+			tx.Vout[i].Multisig = &blockchain.MultisigLock{
+				Threshold: int(vout.MultisigThreshold),
+				Addresses: vout.MultisigAddresses,
+			}
+		}
+		if vout.Htlc != nil {
+			tx.Vout[i].HTLC = &blockchain.HTLCLock{
+				HashType:  vout.Htlc.HashType,
+				Hash:      vout.Htlc.Hash,
+				Recipient: vout.Htlc.Recipient,
+				Sender:    vout.Htlc.Sender,
+				Timeout:   int(vout.Htlc.Timeout),
+			}
+		}
+	}
+
+	return tx
+}
@@ -25,6 +25,7 @@ const (
 type BlockTemplateRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	MinerAddress  string                 `protobuf:"bytes,1,opt,name=miner_address,json=minerAddress,proto3" json:"miner_address,omitempty"`
+	CoinbaseData  string                 `protobuf:"bytes,2,opt,name=coinbase_data,json=coinbaseData,proto3" json:"coinbase_data,omitempty"` // Optional extra data committed to in the coinbase
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -66,6 +67,13 @@ func (x *BlockTemplateRequest) GetMinerAddress() string {
 	return ""
 }
 
+func (x *BlockTemplateRequest) GetCoinbaseData() string {
+	if x != nil {
+		return x.CoinbaseData
+	}
+	return ""
+}
+
 // Block data structure
 type Block struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -73,6 +81,8 @@ type Block struct {
 	PrevBlockHash []byte                 `protobuf:"bytes,2,opt,name=prev_block_hash,json=prevBlockHash,proto3" json:"prev_block_hash,omitempty"`
 	Height        int32                  `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
 	Transactions  []*Transaction         `protobuf:"bytes,4,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	Version       int32                  `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"` // Block header version
+	Bits          int32                  `protobuf:"varint,6,opt,name=bits,proto3" json:"bits,omitempty"`       // Difficulty committed to in the header
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -135,6 +145,20 @@ func (x *Block) GetTransactions() []*Transaction {
 	return nil
 }
 
+func (x *Block) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Block) GetBits() int32 {
+	if x != nil {
+		return x.Bits
+	}
+	return 0
+}
+
 // Response containing a block template
 type BlockTemplateResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -193,7 +217,7 @@ type SubmitBlockRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Block         *Block                 `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
 	BlockHash     []byte                 `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
-	Nonce         int32                  `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Nonce         uint64                 `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -242,7 +266,7 @@ func (x *SubmitBlockRequest) GetBlockHash() []byte {
 	return nil
 }
 
-func (x *SubmitBlockRequest) GetNonce() int32 {
+func (x *SubmitBlockRequest) GetNonce() uint64 {
 	if x != nil {
 		return x.Nonce
 	}
@@ -321,6 +345,9 @@ type Transaction struct {
 	TransactionId []byte                 `protobuf:"bytes,6,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
 	Vin           []*TXInput             `protobuf:"bytes,7,rep,name=vin,proto3" json:"vin,omitempty"`
 	Vout          []*TXOutput            `protobuf:"bytes,8,rep,name=vout,proto3" json:"vout,omitempty"`
+	ExtraNonce    uint64                 `protobuf:"varint,9,opt,name=extra_nonce,json=extraNonce,proto3" json:"extra_nonce,omitempty"` // Rolled by miners in the coinbase once the nonce space is exhausted
+	LockTime      int64                  `protobuf:"varint,10,opt,name=lock_time,json=lockTime,proto3" json:"lock_time,omitempty"`      // Earliest block height or unix time for inclusion
+	Issuance      *TokenIssuance         `protobuf:"bytes,11,opt,name=issuance,proto3" json:"issuance,omitempty"`                       // Set on transactions that create a token
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -411,6 +438,96 @@ func (x *Transaction) GetVout() []*TXOutput {
 	return nil
 }
 
+func (x *Transaction) GetExtraNonce() uint64 {
+	if x != nil {
+		return x.ExtraNonce
+	}
+	return 0
+}
+
+func (x *Transaction) GetLockTime() int64 {
+	if x != nil {
+		return x.LockTime
+	}
+	return 0
+}
+
+func (x *Transaction) GetIssuance() *TokenIssuance {
+	if x != nil {
+		return x.Issuance
+	}
+	return nil
+}
+
+// Definition of a new token
+type TokenIssuance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Supply        uint64                 `protobuf:"varint,3,opt,name=supply,proto3" json:"supply,omitempty"`
+	Metadata      string                 `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenIssuance) Reset() {
+	*x = TokenIssuance{}
+	mi := &file_proto_mining_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenIssuance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenIssuance) ProtoMessage() {}
+
+func (x *TokenIssuance) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenIssuance.ProtoReflect.Descriptor instead.
+func (*TokenIssuance) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TokenIssuance) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TokenIssuance) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *TokenIssuance) GetSupply() uint64 {
+	if x != nil {
+		return x.Supply
+	}
+	return 0
+}
+
+func (x *TokenIssuance) GetMetadata() string {
+	if x != nil {
+		return x.Metadata
+	}
+	return ""
+}
+
 // Transaction Input
 type TXInput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -418,13 +535,16 @@ type TXInput struct {
 	Vout          int32                  `protobuf:"varint,2,opt,name=vout,proto3" json:"vout,omitempty"`
 	Signature     []byte                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
 	PubKey        []byte                 `protobuf:"bytes,4,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"` // Public key bytes
+	Sequence      uint32                 `protobuf:"varint,5,opt,name=sequence,proto3" json:"sequence,omitempty"`          // Relative lock time
+	Signatures    [][]byte               `protobuf:"bytes,6,rep,name=signatures,proto3" json:"signatures,omitempty"`       // Co-signatures when spending a multisig output
+	Preimage      []byte                 `protobuf:"bytes,7,opt,name=preimage,proto3" json:"preimage,omitempty"`           // Redeems an HTLC output
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TXInput) Reset() {
 	*x = TXInput{}
-	mi := &file_proto_mining_proto_msgTypes[6]
+	mi := &file_proto_mining_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -436,7 +556,7 @@ func (x *TXInput) String() string {
 func (*TXInput) ProtoMessage() {}
 
 func (x *TXInput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[6]
+	mi := &file_proto_mining_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -449,7 +569,7 @@ func (x *TXInput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TXInput.ProtoReflect.Descriptor instead.
 func (*TXInput) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{6}
+	return file_proto_mining_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *TXInput) GetTxid() []byte {
@@ -480,18 +600,45 @@ func (x *TXInput) GetPubKey() []byte {
 	return nil
 }
 
+func (x *TXInput) GetSequence() uint32 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *TXInput) GetSignatures() [][]byte {
+	if x != nil {
+		return x.Signatures
+	}
+	return nil
+}
+
+func (x *TXInput) GetPreimage() []byte {
+	if x != nil {
+		return x.Preimage
+	}
+	return nil
+}
+
 // Transaction Output
 type TXOutput struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         float32                `protobuf:"fixed32,1,opt,name=value,proto3" json:"value,omitempty"`
-	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Value             float32                `protobuf:"fixed32,1,opt,name=value,proto3" json:"value,omitempty"`
+	Address           string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	MultisigThreshold int32                  `protobuf:"varint,3,opt,name=multisig_threshold,json=multisigThreshold,proto3" json:"multisig_threshold,omitempty"` // Non-zero for m-of-n outputs
+	MultisigAddresses []string               `protobuf:"bytes,4,rep,name=multisig_addresses,json=multisigAddresses,proto3" json:"multisig_addresses,omitempty"`  // Signers of an m-of-n output
+	Htlc              *HTLCLock              `protobuf:"bytes,5,opt,name=htlc,proto3" json:"htlc,omitempty"`                                                     // Set on hash time-locked outputs
+	Data              []byte                 `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`                                                     // Payload of an unspendable data output
+	TokenId           string                 `protobuf:"bytes,7,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`                                // Token carried by the output
+	TokenAmount       uint64                 `protobuf:"varint,8,opt,name=token_amount,json=tokenAmount,proto3" json:"token_amount,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *TXOutput) Reset() {
 	*x = TXOutput{}
-	mi := &file_proto_mining_proto_msgTypes[7]
+	mi := &file_proto_mining_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -503,7 +650,7 @@ func (x *TXOutput) String() string {
 func (*TXOutput) ProtoMessage() {}
 
 func (x *TXOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[7]
+	mi := &file_proto_mining_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -516,7 +663,7 @@ func (x *TXOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TXOutput.ProtoReflect.Descriptor instead.
 func (*TXOutput) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{7}
+	return file_proto_mining_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *TXOutput) GetValue() float32 {
@@ -533,6 +680,125 @@ func (x *TXOutput) GetAddress() string {
 	return ""
 }
 
+func (x *TXOutput) GetMultisigThreshold() int32 {
+	if x != nil {
+		return x.MultisigThreshold
+	}
+	return 0
+}
+
+func (x *TXOutput) GetMultisigAddresses() []string {
+	if x != nil {
+		return x.MultisigAddresses
+	}
+	return nil
+}
+
+func (x *TXOutput) GetHtlc() *HTLCLock {
+	if x != nil {
+		return x.Htlc
+	}
+	return nil
+}
+
+func (x *TXOutput) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *TXOutput) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *TXOutput) GetTokenAmount() uint64 {
+	if x != nil {
+		return x.TokenAmount
+	}
+	return 0
+}
+
+// Hash time lock of an output
+type HTLCLock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HashType      string                 `protobuf:"bytes,1,opt,name=hash_type,json=hashType,proto3" json:"hash_type,omitempty"` // "sha256" or "keccak256"
+	Hash          []byte                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Recipient     string                 `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"` // Redeems with the preimage
+	Sender        string                 `protobuf:"bytes,4,opt,name=sender,proto3" json:"sender,omitempty"`       // Refunds after the timeout
+	Timeout       int32                  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`    // Block height
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HTLCLock) Reset() {
+	*x = HTLCLock{}
+	mi := &file_proto_mining_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HTLCLock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HTLCLock) ProtoMessage() {}
+
+func (x *HTLCLock) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HTLCLock.ProtoReflect.Descriptor instead.
+func (*HTLCLock) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HTLCLock) GetHashType() string {
+	if x != nil {
+		return x.HashType
+	}
+	return ""
+}
+
+func (x *HTLCLock) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *HTLCLock) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *HTLCLock) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *HTLCLock) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
 // Request to get blockchain status
 type BlockchainStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -542,7 +808,7 @@ type BlockchainStatusRequest struct {
 
 func (x *BlockchainStatusRequest) Reset() {
 	*x = BlockchainStatusRequest{}
-	mi := &file_proto_mining_proto_msgTypes[8]
+	mi := &file_proto_mining_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -554,7 +820,7 @@ func (x *BlockchainStatusRequest) String() string {
 func (*BlockchainStatusRequest) ProtoMessage() {}
 
 func (x *BlockchainStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[8]
+	mi := &file_proto_mining_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -567,7 +833,7 @@ func (x *BlockchainStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockchainStatusRequest.ProtoReflect.Descriptor instead.
 func (*BlockchainStatusRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{8}
+	return file_proto_mining_proto_rawDescGZIP(), []int{10}
 }
 
 // Response containing blockchain status
@@ -582,7 +848,7 @@ type BlockchainStatusResponse struct {
 
 func (x *BlockchainStatusResponse) Reset() {
 	*x = BlockchainStatusResponse{}
-	mi := &file_proto_mining_proto_msgTypes[9]
+	mi := &file_proto_mining_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -594,7 +860,7 @@ func (x *BlockchainStatusResponse) String() string {
 func (*BlockchainStatusResponse) ProtoMessage() {}
 
 func (x *BlockchainStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[9]
+	mi := &file_proto_mining_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -607,7 +873,7 @@ func (x *BlockchainStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockchainStatusResponse.ProtoReflect.Descriptor instead.
 func (*BlockchainStatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{9}
+	return file_proto_mining_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *BlockchainStatusResponse) GetHeight() int32 {
@@ -640,7 +906,7 @@ type PendingTransactionsRequest struct {
 
 func (x *PendingTransactionsRequest) Reset() {
 	*x = PendingTransactionsRequest{}
-	mi := &file_proto_mining_proto_msgTypes[10]
+	mi := &file_proto_mining_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -652,7 +918,7 @@ func (x *PendingTransactionsRequest) String() string {
 func (*PendingTransactionsRequest) ProtoMessage() {}
 
 func (x *PendingTransactionsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[10]
+	mi := &file_proto_mining_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -665,7 +931,7 @@ func (x *PendingTransactionsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PendingTransactionsRequest.ProtoReflect.Descriptor instead.
 func (*PendingTransactionsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{10}
+	return file_proto_mining_proto_rawDescGZIP(), []int{12}
 }
 
 // Response containing pending transactions
@@ -678,7 +944,7 @@ type PendingTransactionsResponse struct {
 
 func (x *PendingTransactionsResponse) Reset() {
 	*x = PendingTransactionsResponse{}
-	mi := &file_proto_mining_proto_msgTypes[11]
+	mi := &file_proto_mining_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -690,7 +956,7 @@ func (x *PendingTransactionsResponse) String() string {
 func (*PendingTransactionsResponse) ProtoMessage() {}
 
 func (x *PendingTransactionsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_mining_proto_msgTypes[11]
+	mi := &file_proto_mining_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -703,7 +969,7 @@ func (x *PendingTransactionsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PendingTransactionsResponse.ProtoReflect.Descriptor instead.
 func (*PendingTransactionsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_mining_proto_rawDescGZIP(), []int{11}
+	return file_proto_mining_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *PendingTransactionsResponse) GetTransactions() []*Transaction {
@@ -713,18 +979,241 @@ func (x *PendingTransactionsResponse) GetTransactions() []*Transaction {
 	return nil
 }
 
+// Request to generate blocks on demand
+type GenerateBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        int32                  `protobuf:"varint,1,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"` // Receives the block rewards
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateBlocksRequest) Reset() {
+	*x = GenerateBlocksRequest{}
+	mi := &file_proto_mining_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateBlocksRequest) ProtoMessage() {}
+
+func (x *GenerateBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateBlocksRequest.ProtoReflect.Descriptor instead.
+func (*GenerateBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GenerateBlocksRequest) GetBlocks() int32 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *GenerateBlocksRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+// Response containing the generated blocks
+type GenerateBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BlockHashes   []string               `protobuf:"bytes,1,rep,name=block_hashes,json=blockHashes,proto3" json:"block_hashes,omitempty"`
+	Height        int32                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateBlocksResponse) Reset() {
+	*x = GenerateBlocksResponse{}
+	mi := &file_proto_mining_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateBlocksResponse) ProtoMessage() {}
+
+func (x *GenerateBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateBlocksResponse.ProtoReflect.Descriptor instead.
+func (*GenerateBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GenerateBlocksResponse) GetBlockHashes() []string {
+	if x != nil {
+		return x.BlockHashes
+	}
+	return nil
+}
+
+func (x *GenerateBlocksResponse) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+// Request to raise the fee of a pending transaction
+type BumpFeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId []byte                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	PrivateKey    string                 `protobuf:"bytes,2,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"` // Key of the sender, used to re-sign the replacement
+	Fee           float32                `protobuf:"fixed32,3,opt,name=fee,proto3" json:"fee,omitempty"`                               // New absolute fee
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BumpFeeRequest) Reset() {
+	*x = BumpFeeRequest{}
+	mi := &file_proto_mining_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BumpFeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BumpFeeRequest) ProtoMessage() {}
+
+func (x *BumpFeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BumpFeeRequest.ProtoReflect.Descriptor instead.
+func (*BumpFeeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *BumpFeeRequest) GetTransactionId() []byte {
+	if x != nil {
+		return x.TransactionId
+	}
+	return nil
+}
+
+func (x *BumpFeeRequest) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+func (x *BumpFeeRequest) GetFee() float32 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+// Response containing the replacement transaction
+type BumpFeeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId []byte                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	Fee           float32                `protobuf:"fixed32,2,opt,name=fee,proto3" json:"fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BumpFeeResponse) Reset() {
+	*x = BumpFeeResponse{}
+	mi := &file_proto_mining_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BumpFeeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BumpFeeResponse) ProtoMessage() {}
+
+func (x *BumpFeeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_mining_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BumpFeeResponse.ProtoReflect.Descriptor instead.
+func (*BumpFeeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_mining_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *BumpFeeResponse) GetTransactionId() []byte {
+	if x != nil {
+		return x.TransactionId
+	}
+	return nil
+}
+
+func (x *BumpFeeResponse) GetFee() float32 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
 var File_proto_mining_proto protoreflect.FileDescriptor
 
 const file_proto_mining_proto_rawDesc = "" +
 	"\n" +
-	"\x12proto/mining.proto\x12\x05proto\";\n" +
+	"\x12proto/mining.proto\x12\x05proto\"`\n" +
 	"\x14BlockTemplateRequest\x12#\n" +
-	"\rminer_address\x18\x01 \x01(\tR\fminerAddress\"\x9d\x01\n" +
+	"\rminer_address\x18\x01 \x01(\tR\fminerAddress\x12#\n" +
+	"\rcoinbase_data\x18\x02 \x01(\tR\fcoinbaseData\"\xcb\x01\n" +
 	"\x05Block\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12&\n" +
 	"\x0fprev_block_hash\x18\x02 \x01(\fR\rprevBlockHash\x12\x16\n" +
 	"\x06height\x18\x03 \x01(\x05R\x06height\x126\n" +
-	"\ftransactions\x18\x04 \x03(\v2\x12.proto.TransactionR\ftransactions\"[\n" +
+	"\ftransactions\x18\x04 \x03(\v2\x12.proto.TransactionR\ftransactions\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\x05R\aversion\x12\x12\n" +
+	"\x04bits\x18\x06 \x01(\x05R\x04bits\"[\n" +
 	"\x15BlockTemplateResponse\x12\"\n" +
 	"\x05block\x18\x01 \x01(\v2\f.proto.BlockR\x05block\x12\x1e\n" +
 	"\n" +
@@ -734,12 +1223,12 @@ const file_proto_mining_proto_rawDesc = "" +
 	"\x05block\x18\x01 \x01(\v2\f.proto.BlockR\x05block\x12\x1d\n" +
 	"\n" +
 	"block_hash\x18\x02 \x01(\fR\tblockHash\x12\x14\n" +
-	"\x05nonce\x18\x03 \x01(\x05R\x05nonce\"s\n" +
+	"\x05nonce\x18\x03 \x01(\x04R\x05nonce\"s\n" +
 	"\x13SubmitBlockResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
 	"\n" +
 	"block_hash\x18\x02 \x01(\tR\tblockHash\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\xe7\x01\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\xd7\x02\n" +
 	"\vTransaction\x12\x12\n" +
 	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
 	"\x02to\x18\x02 \x01(\tR\x02to\x12\x16\n" +
@@ -748,15 +1237,42 @@ const file_proto_mining_proto_rawDesc = "" +
 	"\tsignature\x18\x05 \x01(\fR\tsignature\x12%\n" +
 	"\x0etransaction_id\x18\x06 \x01(\fR\rtransactionId\x12 \n" +
 	"\x03vin\x18\a \x03(\v2\x0e.proto.TXInputR\x03vin\x12#\n" +
-	"\x04vout\x18\b \x03(\v2\x0f.proto.TXOutputR\x04vout\"h\n" +
+	"\x04vout\x18\b \x03(\v2\x0f.proto.TXOutputR\x04vout\x12\x1f\n" +
+	"\vextra_nonce\x18\t \x01(\x04R\n" +
+	"extraNonce\x12\x1b\n" +
+	"\tlock_time\x18\n" +
+	" \x01(\x03R\blockTime\x120\n" +
+	"\bissuance\x18\v \x01(\v2\x14.proto.TokenIssuanceR\bissuance\"o\n" +
+	"\rTokenIssuance\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x16\n" +
+	"\x06supply\x18\x03 \x01(\x04R\x06supply\x12\x1a\n" +
+	"\bmetadata\x18\x04 \x01(\tR\bmetadata\"\xc0\x01\n" +
 	"\aTXInput\x12\x12\n" +
 	"\x04txid\x18\x01 \x01(\fR\x04txid\x12\x12\n" +
 	"\x04vout\x18\x02 \x01(\x05R\x04vout\x12\x1c\n" +
 	"\tsignature\x18\x03 \x01(\fR\tsignature\x12\x17\n" +
-	"\apub_key\x18\x04 \x01(\fR\x06pubKey\":\n" +
+	"\apub_key\x18\x04 \x01(\fR\x06pubKey\x12\x1a\n" +
+	"\bsequence\x18\x05 \x01(\rR\bsequence\x12\x1e\n" +
+	"\n" +
+	"signatures\x18\x06 \x03(\fR\n" +
+	"signatures\x12\x1a\n" +
+	"\bpreimage\x18\a \x01(\fR\bpreimage\"\x8f\x02\n" +
 	"\bTXOutput\x12\x14\n" +
 	"\x05value\x18\x01 \x01(\x02R\x05value\x12\x18\n" +
-	"\aaddress\x18\x02 \x01(\tR\aaddress\"\x19\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12-\n" +
+	"\x12multisig_threshold\x18\x03 \x01(\x05R\x11multisigThreshold\x12-\n" +
+	"\x12multisig_addresses\x18\x04 \x03(\tR\x11multisigAddresses\x12#\n" +
+	"\x04htlc\x18\x05 \x01(\v2\x0f.proto.HTLCLockR\x04htlc\x12\x12\n" +
+	"\x04data\x18\x06 \x01(\fR\x04data\x12\x19\n" +
+	"\btoken_id\x18\a \x01(\tR\atokenId\x12!\n" +
+	"\ftoken_amount\x18\b \x01(\x04R\vtokenAmount\"\x8b\x01\n" +
+	"\bHTLCLock\x12\x1b\n" +
+	"\thash_type\x18\x01 \x01(\tR\bhashType\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\fR\x04hash\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12\x16\n" +
+	"\x06sender\x18\x04 \x01(\tR\x06sender\x12\x18\n" +
+	"\atimeout\x18\x05 \x01(\x05R\atimeout\"\x19\n" +
 	"\x17BlockchainStatusRequest\"~\n" +
 	"\x18BlockchainStatusResponse\x12\x16\n" +
 	"\x06height\x18\x01 \x01(\x05R\x06height\x12*\n" +
@@ -766,12 +1282,28 @@ const file_proto_mining_proto_rawDesc = "" +
 	"difficulty\"\x1c\n" +
 	"\x1aPendingTransactionsRequest\"U\n" +
 	"\x1bPendingTransactionsResponse\x126\n" +
-	"\ftransactions\x18\x01 \x03(\v2\x12.proto.TransactionR\ftransactions2\xe5\x02\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x12.proto.TransactionR\ftransactions\"I\n" +
+	"\x15GenerateBlocksRequest\x12\x16\n" +
+	"\x06blocks\x18\x01 \x01(\x05R\x06blocks\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\"S\n" +
+	"\x16GenerateBlocksResponse\x12!\n" +
+	"\fblock_hashes\x18\x01 \x03(\tR\vblockHashes\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\"j\n" +
+	"\x0eBumpFeeRequest\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\fR\rtransactionId\x12\x1f\n" +
+	"\vprivate_key\x18\x02 \x01(\tR\n" +
+	"privateKey\x12\x10\n" +
+	"\x03fee\x18\x03 \x01(\x02R\x03fee\"J\n" +
+	"\x0fBumpFeeResponse\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\fR\rtransactionId\x12\x10\n" +
+	"\x03fee\x18\x02 \x01(\x02R\x03fee2\xf2\x03\n" +
 	"\rMiningService\x12O\n" +
 	"\x10GetBlockTemplate\x12\x1b.proto.BlockTemplateRequest\x1a\x1c.proto.BlockTemplateResponse\"\x00\x12F\n" +
 	"\vSubmitBlock\x12\x19.proto.SubmitBlockRequest\x1a\x1a.proto.SubmitBlockResponse\"\x00\x12X\n" +
 	"\x13GetBlockchainStatus\x12\x1e.proto.BlockchainStatusRequest\x1a\x1f.proto.BlockchainStatusResponse\"\x00\x12a\n" +
-	"\x16GetPendingTransactions\x12!.proto.PendingTransactionsRequest\x1a\".proto.PendingTransactionsResponse\"\x00B\tZ\a./protob\x06proto3"
+	"\x16GetPendingTransactions\x12!.proto.PendingTransactionsRequest\x1a\".proto.PendingTransactionsResponse\"\x00\x12O\n" +
+	"\x0eGenerateBlocks\x12\x1c.proto.GenerateBlocksRequest\x1a\x1d.proto.GenerateBlocksResponse\"\x00\x12:\n" +
+	"\aBumpFee\x12\x15.proto.BumpFeeRequest\x1a\x16.proto.BumpFeeResponse\"\x00B\tZ\a./protob\x06proto3"
 
 var (
 	file_proto_mining_proto_rawDescOnce sync.Once
@@ -785,7 +1317,7 @@ func file_proto_mining_proto_rawDescGZIP() []byte {
 	return file_proto_mining_proto_rawDescData
 }
 
-var file_proto_mining_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proto_mining_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_proto_mining_proto_goTypes = []any{
 	(*BlockTemplateRequest)(nil),        // 0: proto.BlockTemplateRequest
 	(*Block)(nil),                       // 1: proto.Block
@@ -793,33 +1325,45 @@ var file_proto_mining_proto_goTypes = []any{
 	(*SubmitBlockRequest)(nil),          // 3: proto.SubmitBlockRequest
 	(*SubmitBlockResponse)(nil),         // 4: proto.SubmitBlockResponse
 	(*Transaction)(nil),                 // 5: proto.Transaction
-	(*TXInput)(nil),                     // 6: proto.TXInput
-	(*TXOutput)(nil),                    // 7: proto.TXOutput
-	(*BlockchainStatusRequest)(nil),     // 8: proto.BlockchainStatusRequest
-	(*BlockchainStatusResponse)(nil),    // 9: proto.BlockchainStatusResponse
-	(*PendingTransactionsRequest)(nil),  // 10: proto.PendingTransactionsRequest
-	(*PendingTransactionsResponse)(nil), // 11: proto.PendingTransactionsResponse
+	(*TokenIssuance)(nil),               // 6: proto.TokenIssuance
+	(*TXInput)(nil),                     // 7: proto.TXInput
+	(*TXOutput)(nil),                    // 8: proto.TXOutput
+	(*HTLCLock)(nil),                    // 9: proto.HTLCLock
+	(*BlockchainStatusRequest)(nil),     // 10: proto.BlockchainStatusRequest
+	(*BlockchainStatusResponse)(nil),    // 11: proto.BlockchainStatusResponse
+	(*PendingTransactionsRequest)(nil),  // 12: proto.PendingTransactionsRequest
+	(*PendingTransactionsResponse)(nil), // 13: proto.PendingTransactionsResponse
+	(*GenerateBlocksRequest)(nil),       // 14: proto.GenerateBlocksRequest
+	(*GenerateBlocksResponse)(nil),      // 15: proto.GenerateBlocksResponse
+	(*BumpFeeRequest)(nil),              // 16: proto.BumpFeeRequest
+	(*BumpFeeResponse)(nil),             // 17: proto.BumpFeeResponse
 }
 var file_proto_mining_proto_depIdxs = []int32{
 	5,  // 0: proto.Block.transactions:type_name -> proto.Transaction
 	1,  // 1: proto.BlockTemplateResponse.block:type_name -> proto.Block
 	1,  // 2: proto.SubmitBlockRequest.block:type_name -> proto.Block
-	6,  // 3: proto.Transaction.vin:type_name -> proto.TXInput
-	7,  // 4: proto.Transaction.vout:type_name -> proto.TXOutput
-	5,  // 5: proto.PendingTransactionsResponse.transactions:type_name -> proto.Transaction
-	0,  // 6: proto.MiningService.GetBlockTemplate:input_type -> proto.BlockTemplateRequest
-	3,  // 7: proto.MiningService.SubmitBlock:input_type -> proto.SubmitBlockRequest
-	8,  // 8: proto.MiningService.GetBlockchainStatus:input_type -> proto.BlockchainStatusRequest
-	10, // 9: proto.MiningService.GetPendingTransactions:input_type -> proto.PendingTransactionsRequest
-	2,  // 10: proto.MiningService.GetBlockTemplate:output_type -> proto.BlockTemplateResponse
-	4,  // 11: proto.MiningService.SubmitBlock:output_type -> proto.SubmitBlockResponse
-	9,  // 12: proto.MiningService.GetBlockchainStatus:output_type -> proto.BlockchainStatusResponse
-	11, // 13: proto.MiningService.GetPendingTransactions:output_type -> proto.PendingTransactionsResponse
-	10, // [10:14] is the sub-list for method output_type
-	6,  // [6:10] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	7,  // 3: proto.Transaction.vin:type_name -> proto.TXInput
+	8,  // 4: proto.Transaction.vout:type_name -> proto.TXOutput
+	6,  // 5: proto.Transaction.issuance:type_name -> proto.TokenIssuance
+	9,  // 6: proto.TXOutput.htlc:type_name -> proto.HTLCLock
+	5,  // 7: proto.PendingTransactionsResponse.transactions:type_name -> proto.Transaction
+	0,  // 8: proto.MiningService.GetBlockTemplate:input_type -> proto.BlockTemplateRequest
+	3,  // 9: proto.MiningService.SubmitBlock:input_type -> proto.SubmitBlockRequest
+	10, // 10: proto.MiningService.GetBlockchainStatus:input_type -> proto.BlockchainStatusRequest
+	12, // 11: proto.MiningService.GetPendingTransactions:input_type -> proto.PendingTransactionsRequest
+	14, // 12: proto.MiningService.GenerateBlocks:input_type -> proto.GenerateBlocksRequest
+	16, // 13: proto.MiningService.BumpFee:input_type -> proto.BumpFeeRequest
+	2,  // 14: proto.MiningService.GetBlockTemplate:output_type -> proto.BlockTemplateResponse
+	4,  // 15: proto.MiningService.SubmitBlock:output_type -> proto.SubmitBlockResponse
+	11, // 16: proto.MiningService.GetBlockchainStatus:output_type -> proto.BlockchainStatusResponse
+	13, // 17: proto.MiningService.GetPendingTransactions:output_type -> proto.PendingTransactionsResponse
+	15, // 18: proto.MiningService.GenerateBlocks:output_type -> proto.GenerateBlocksResponse
+	17, // 19: proto.MiningService.BumpFee:output_type -> proto.BumpFeeResponse
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_proto_mining_proto_init() }
@@ -833,7 +1377,7 @@ func file_proto_mining_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_mining_proto_rawDesc), len(file_proto_mining_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   12,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,8 @@ const (
 	MiningService_SubmitBlock_FullMethodName            = "/proto.MiningService/SubmitBlock"
 	MiningService_GetBlockchainStatus_FullMethodName    = "/proto.MiningService/GetBlockchainStatus"
 	MiningService_GetPendingTransactions_FullMethodName = "/proto.MiningService/GetPendingTransactions"
+	MiningService_GenerateBlocks_FullMethodName         = "/proto.MiningService/GenerateBlocks"
+	MiningService_BumpFee_FullMethodName                = "/proto.MiningService/BumpFee"
 )
 
 // MiningServiceClient is the client API for MiningService service.
@@ -39,6 +41,11 @@ type MiningServiceClient interface {
 	GetBlockchainStatus(ctx context.Context, in *BlockchainStatusRequest, opts ...grpc.CallOption) (*BlockchainStatusResponse, error)
 	// Get pending transactions
 	GetPendingTransactions(ctx context.Context, in *PendingTransactionsRequest, opts ...grpc.CallOption) (*PendingTransactionsResponse, error)
+	// Deprecated: no longer served. Blocks are generated on the node's admin listener.
+	GenerateBlocks(ctx context.Context, in *GenerateBlocksRequest, opts ...grpc.CallOption) (*GenerateBlocksResponse, error)
+	// Deprecated: no longer served. Fees are bumped with /transaction/bumpfee and
+	// the replacement is signed by the client.
+	BumpFee(ctx context.Context, in *BumpFeeRequest, opts ...grpc.CallOption) (*BumpFeeResponse, error)
 }
 
 type miningServiceClient struct {
@@ -89,6 +96,26 @@ func (c *miningServiceClient) GetPendingTransactions(ctx context.Context, in *Pe
 	return out, nil
 }
 
+func (c *miningServiceClient) GenerateBlocks(ctx context.Context, in *GenerateBlocksRequest, opts ...grpc.CallOption) (*GenerateBlocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateBlocksResponse)
+	err := c.cc.Invoke(ctx, MiningService_GenerateBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *miningServiceClient) BumpFee(ctx context.Context, in *BumpFeeRequest, opts ...grpc.CallOption) (*BumpFeeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BumpFeeResponse)
+	err := c.cc.Invoke(ctx, MiningService_BumpFee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MiningServiceServer is the server API for MiningService service.
 // All implementations must embed UnimplementedMiningServiceServer
 // for forward compatibility.
@@ -103,6 +130,11 @@ type MiningServiceServer interface {
 	GetBlockchainStatus(context.Context, *BlockchainStatusRequest) (*BlockchainStatusResponse, error)
 	// Get pending transactions
 	GetPendingTransactions(context.Context, *PendingTransactionsRequest) (*PendingTransactionsResponse, error)
+	// Deprecated: no longer served. Blocks are generated on the node's admin listener.
+	GenerateBlocks(context.Context, *GenerateBlocksRequest) (*GenerateBlocksResponse, error)
+	// Deprecated: no longer served. Fees are bumped with /transaction/bumpfee and
+	// the replacement is signed by the client.
+	BumpFee(context.Context, *BumpFeeRequest) (*BumpFeeResponse, error)
 	mustEmbedUnimplementedMiningServiceServer()
 }
 
@@ -125,6 +157,12 @@ func (UnimplementedMiningServiceServer) GetBlockchainStatus(context.Context, *Bl
 func (UnimplementedMiningServiceServer) GetPendingTransactions(context.Context, *PendingTransactionsRequest) (*PendingTransactionsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPendingTransactions not implemented")
 }
+func (UnimplementedMiningServiceServer) GenerateBlocks(context.Context, *GenerateBlocksRequest) (*GenerateBlocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateBlocks not implemented")
+}
+func (UnimplementedMiningServiceServer) BumpFee(context.Context, *BumpFeeRequest) (*BumpFeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BumpFee not implemented")
+}
 func (UnimplementedMiningServiceServer) mustEmbedUnimplementedMiningServiceServer() {}
 func (UnimplementedMiningServiceServer) testEmbeddedByValue()                       {}
 
@@ -218,6 +256,42 @@ func _MiningService_GetPendingTransactions_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MiningService_GenerateBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MiningServiceServer).GenerateBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MiningService_GenerateBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MiningServiceServer).GenerateBlocks(ctx, req.(*GenerateBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MiningService_BumpFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BumpFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MiningServiceServer).BumpFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MiningService_BumpFee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MiningServiceServer).BumpFee(ctx, req.(*BumpFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MiningService_ServiceDesc is the grpc.ServiceDesc for MiningService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -241,6 +315,14 @@ var MiningService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetPendingTransactions",
 			Handler:    _MiningService_GetPendingTransactions_Handler,
 		},
+		{
+			MethodName: "GenerateBlocks",
+			Handler:    _MiningService_GenerateBlocks_Handler,
+		},
+		{
+			MethodName: "BumpFee",
+			Handler:    _MiningService_BumpFee_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/mining.proto",
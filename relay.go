@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"dyp_chain/api"
+	blockchain "dyp_chain/blockchain"
+)
+
+// relayQueueSize is how many connected blocks may wait to be relayed
+const relayQueueSize = 256
+
+// relayRetries is how often a block refused for rate limiting is sent again
+const relayRetries = 5
+
+// blockRelay pushes every block connected to the local chain, sealed here or
+// imported, to the /blocks/import route of the peers. Signers of a
+// proof-of-authority network list each other, and their followers, as peers.
+// A peer that is behind is sent the blocks it misses first.
+type blockRelay struct {
+	bc     *blockchain.Blockchain
+	peers  []string
+	blocks chan *blockchain.Block
+	client *http.Client
+}
+
+// newBlockRelay starts relaying the blocks connected to bc to peers, a comma
+// separated list of node API URLs such as http://10.0.0.2:8080
+func newBlockRelay(bc *blockchain.Blockchain, peers string) *blockRelay {
+	r := &blockRelay{
+		bc:     bc,
+		blocks: make(chan *blockchain.Block, relayQueueSize),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, peer := range strings.Split(peers, ",") {
+		if peer = strings.TrimRight(strings.TrimSpace(peer), "/"); peer != "" {
+			r.peers = append(r.peers, peer)
+		}
+	}
+
+	go r.run()
+	return r
+}
+
+// BlockConnected queues block for the peers without blocking the chain
+func (r *blockRelay) BlockConnected(block *blockchain.Block) {
+	select {
+	case r.blocks <- block:
+	default:
+		log.Printf("[Relay] Queue full, block %d is not relayed", block.Height)
+	}
+}
+
+func (r *blockRelay) run() {
+	for block := range r.blocks {
+		for _, peer := range r.peers {
+			if err := r.push(peer, block); err != nil {
+				log.Printf("[Relay] Failed to send block %d to %s: %v", block.Height, peer, err)
+			}
+		}
+	}
+}
+
+// push sends block to peer, preceded by the blocks the peer is missing
+func (r *blockRelay) push(peer string, block *blockchain.Block) error {
+	resp, status, err := r.send(peer, block)
+	if err != nil || status != http.StatusConflict {
+		return err
+	}
+	if resp.Height >= block.Height {
+		return fmt.Errorf("peer is at height %d and does not link the block", resp.Height)
+	}
+
+	log.Printf("[Relay] %s is at height %d, sending blocks %d to %d", peer, resp.Height, resp.Height+1, block.Height)
+	for height := resp.Height + 1; height < block.Height; height++ {
+		missing, err := r.bc.GetBlockByHeight(height)
+		if err != nil {
+			return err
+		}
+		if _, _, err := r.send(peer, missing); err != nil {
+			return err
+		}
+	}
+
+	_, status, err = r.send(peer, block)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("peer refused the block after catching up")
+	}
+	return err
+}
+
+// send posts one block to peer and returns the peer's answer. A conflict,
+// when the peer is behind, is returned with the peer's height and no error.
+func (r *blockRelay) send(peer string, block *blockchain.Block) (*api.ImportBlockResponse, int, error) {
+	body, err := json.Marshal(api.ImportBlockRequest{
+		Header: hex.EncodeToString(block.BlockHeader.Serialize()),
+		Body:   hex.EncodeToString(block.SerializeBody()),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpResp, err := r.client.Post(peer+"/blocks/import", "application/json", bytes.NewReader(body))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if httpResp.StatusCode == http.StatusTooManyRequests && attempt < relayRetries {
+			httpResp.Body.Close()
+			time.Sleep(time.Second)
+			continue
+		}
+
+		defer httpResp.Body.Close()
+		if httpResp.StatusCode != http.StatusOK && httpResp.StatusCode != http.StatusConflict {
+			msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+			return nil, httpResp.StatusCode, fmt.Errorf("%s", strings.TrimSpace(string(msg)))
+		}
+
+		var resp api.ImportBlockResponse
+		if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+			return nil, httpResp.StatusCode, err
+		}
+		return &resp, httpResp.StatusCode, nil
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readSecret prompts on stderr and reads a line from stdin without echoing
+// it. Input that is not a terminal, such as a pipe, is read as is.
+func readSecret(prompt string) string {
+	fd := int(os.Stdin.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return readLine(prompt)
+	}
+
+	noEcho := *termios
+	noEcho.Lflag &^= unix.ECHO
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &noEcho); err != nil {
+		return readLine(prompt)
+	}
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+
+	line := readLine(prompt)
+	fmt.Fprintln(os.Stderr)
+	return line
+}
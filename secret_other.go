@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// readSecret prompts on stderr and reads a line from stdin. Echo is only
+// turned off on Linux.
+func readSecret(prompt string) string {
+	return readLine(prompt)
+}